@@ -0,0 +1,119 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/xgfone/gconf/v6"
+)
+
+// AdminConfig is the live configuration managed by AdminHandler.
+//
+// For PUT, the absent fields are not changed.
+type AdminConfig struct {
+	LogReqBody  *bool    `json:"reqbody,omitempty"`
+	LogRespBody *bool    `json:"respbody,omitempty"`
+	BodyMaxLen  *int     `json:"bodymaxlen,omitempty"`
+	BodyTypes   []string `json:"bodytypes,omitempty"`
+	IgnorePaths []string `json:"ignorepaths,omitempty"`
+}
+
+// AdminHandler returns a http handler to get the live configuration by GET,
+// and update it by PUT with AdminConfig as the JSON body at runtime,
+// such as to enable the body logging for a few minutes during an incident.
+//
+// The handler does not authenticate the request, so it must be protected
+// by the previous middleware or only be exposed on the internal address.
+func AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var ac AdminConfig
+			if err := json.NewDecoder(r.Body).Decode(&ac); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := updateconfig(ac); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		c := loadconfig()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(AdminConfig{
+			LogReqBody:  &c.LogReqBody,
+			LogRespBody: &c.LogRespBody,
+			BodyMaxLen:  &c.BodyMaxLen,
+			BodyTypes:   c.BodyTypes,
+			IgnorePaths: c.IgnorePaths,
+		})
+	})
+}
+
+// updateconfig validates and updates the options by the admin config.
+func updateconfig(ac AdminConfig) (err error) {
+	old := loadconfig().clone()
+	c := old.clone()
+	if ac.LogReqBody != nil {
+		c.LogReqBody = *ac.LogReqBody
+	}
+	if ac.LogRespBody != nil {
+		c.LogRespBody = *ac.LogRespBody
+	}
+	if ac.BodyMaxLen != nil {
+		c.BodyMaxLen = *ac.BodyMaxLen
+	}
+	if ac.BodyTypes != nil {
+		c.BodyTypes = ac.BodyTypes
+	}
+	if ac.IgnorePaths != nil {
+		c.IgnorePaths = ac.IgnorePaths
+	}
+	if err = c.Validate(); err != nil {
+		return
+	}
+
+	// Update all the options together like LoadConfigFile.
+	loads := make(map[string]interface{}, 4)
+	if ac.LogReqBody != nil {
+		loads[logReqBody.Name()] = c.LogReqBody
+	}
+	if ac.LogRespBody != nil {
+		loads[logRespBody.Name()] = c.LogRespBody
+	}
+	if ac.BodyMaxLen != nil {
+		loads[logBodyMaxLen.Name()] = c.BodyMaxLen
+	}
+	if ac.BodyTypes != nil {
+		loads[logBodyTypes.Name()] = c.BodyTypes
+	}
+	if err = gconf.Conf.LoadMap(loads, true); err != nil {
+		return
+	}
+	if ac.IgnorePaths != nil {
+		setignorepaths(c.IgnorePaths)
+	}
+
+	notifyconfigchange(old, EffectiveConfig())
+	return
+}
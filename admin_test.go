@@ -0,0 +1,77 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandler(t *testing.T) {
+	defer setignorepaths(loadignorepaths())
+	defer func(types []string) { _ = logBodyTypes.Set(types) }(logBodyTypes.Get())
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logBodyMaxLen.Set(2048)
+	}()
+
+	serve := func(method, body string) (int, AdminConfig) {
+		rec := httptest.NewRecorder()
+		AdminHandler().ServeHTTP(rec, httptest.NewRequest(method, "/admin", strings.NewReader(body)))
+
+		var ac AdminConfig
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &ac); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec.Code, ac
+	}
+
+	code, ac := serve(http.MethodPut, `{"reqbody":true,"bodymaxlen":16,"bodytypes":["application/json"],"ignorepaths":["/health"]}`)
+	if code != http.StatusOK {
+		t.Fatalf("expect status %d, but got %d", http.StatusOK, code)
+	}
+	if !*ac.LogReqBody || *ac.LogRespBody || *ac.BodyMaxLen != 16 ||
+		!slices.Equal(ac.BodyTypes, []string{"application/json"}) ||
+		!slices.Equal(ac.IgnorePaths, []string{"/health"}) {
+		t.Errorf("unexpected config %+v", ac)
+	}
+
+	if !logReqBody.Get() || logBodyMaxLen.Get() != 16 {
+		t.Error("the options are not updated")
+	}
+	if Enabled(httptest.NewRequest(http.MethodGet, "/health", nil)) {
+		t.Error("expect the path /health to be ignored")
+	}
+
+	if code, _ = serve(http.MethodPut, `{"reqbody":false,"bodymaxlen":-1}`); code != http.StatusBadRequest {
+		t.Errorf("expect status %d, but got %d", http.StatusBadRequest, code)
+	}
+	if !logReqBody.Get() {
+		t.Error("the options are updated by the invalid config")
+	}
+
+	if code, ac = serve(http.MethodGet, ""); code != http.StatusOK || *ac.BodyMaxLen != 16 {
+		t.Errorf("unexpected response %d %+v", code, ac)
+	}
+	if code, _ = serve(http.MethodPost, ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("expect status %d, but got %d", http.StatusMethodNotAllowed, code)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// CapturedRequest is the request written into the directory
+// configured by the option capturedir, which can be replayed
+// by LoadCapturedRequest.
+//
+// The headers and the JSON body are redacted like the logs.
+type CapturedRequest struct {
+	Time   time.Time   `json:"time"`
+	Method string      `json:"method"`
+	Scheme string      `json:"scheme"`
+	Host   string      `json:"host"`
+	URI    string      `json:"uri"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+var (
+	capturedseq   atomic.Uint64
+	capturedfiles atomic.Int64
+)
+
+// iscaptured decides whether to write the request into capturedir.
+func iscaptured(c *Config) bool {
+	switch {
+	case c.CaptureDir == "":
+		return false
+	case c.CaptureMaxFiles > 0 && capturedfiles.Load() >= int64(c.CaptureMaxFiles):
+		return false
+	case c.CaptureSampleRate >= 1:
+		return true
+	case c.CaptureSampleRate <= 0:
+		return false
+	default:
+		return rand.Float64() < c.CaptureSampleRate
+	}
+}
+
+// capture writes the sampled request into capturedir.
+//
+// The request whose body is not captured wholly is not written,
+// since it cannot be replayed.
+func capture(c *Config, r *http.Request) {
+	if captured, _ := r.Context().Value(capturekey).(bool); !captured || c.CaptureDir == "" {
+		return
+	}
+
+	req := CapturedRequest{
+		Time:   time.Now(),
+		Method: r.Method,
+		Scheme: "http",
+		Host:   r.Host,
+		URI:    getrequesturi(c, r),
+		Header: redactheaders(c, r.Header),
+	}
+	if r.TLS != nil {
+		req.Scheme = "https"
+	}
+
+	if reqbody, _ := r.Context().Value(reqbodykey).(*reqbody); reqbody == nil || !reqbody.whole(c.BodyMaxLen) {
+		if r.ContentLength != 0 {
+			return
+		}
+	} else {
+		data, ok := redactbody(c, reqbody.data, reqbody.ct)
+		if !ok {
+			return
+		}
+		req.Body = string(data)
+	}
+
+	if c.CaptureMaxFiles > 0 && capturedfiles.Add(1) > int64(c.CaptureMaxFiles) {
+		return
+	}
+
+	data, err := json.MarshalIndent(req, "", "  ")
+	if err == nil {
+		name := fmt.Sprintf("%d-%d.json", req.Time.UnixNano(), capturedseq.Add(1))
+		err = os.WriteFile(filepath.Join(c.CaptureDir, name), data, 0o600)
+	}
+	if err != nil {
+		slog.Error("fail to write the captured request", "dir", c.CaptureDir,
+			"method", r.Method, "path", req.URI, "err", err)
+	}
+}
+
+// LoadCapturedRequest loads the request written into capturedir,
+// and returns a new client request to replay it against host,
+// such as "http://127.0.0.1:8080". If host is empty, use the original.
+func LoadCapturedRequest(filename, host string) (*http.Request, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var req CapturedRequest
+	if err = json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("invalid captured request '%s': %w", filename, err)
+	}
+
+	if host == "" {
+		host = req.Scheme + "://" + req.Host
+	}
+
+	r, err := http.NewRequest(req.Method, strings.TrimSuffix(host, "/")+req.URI, strings.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	r.Header = req.Header.Clone()
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+	r.Header.Del("Content-Length")
+	return r, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCaptureDir(t *testing.T) {
+	dir := t.TempDir()
+	_ = logCaptureDir.Set(dir)
+	_ = logCaptureMaxFiles.Set(2)
+	_ = logBodyMaxLen.Set(8)
+	defer func() {
+		_ = logCaptureDir.Set("")
+		_ = logCaptureMaxFiles.Set(1000)
+		_ = logBodyMaxLen.Set(optdefault[int](logBodyMaxLen))
+		capturedfiles.Store(0)
+	}()
+
+	for _, body := range []string{"abc", "too large body", "xyz", "ignored"} {
+		req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("Authorization", "Bearer token")
+		attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.ReadAll(r.Body)
+		}, req)
+
+		// The body is captured only for capturedir, but not logged.
+		if _, ok := attrs["reqbodylen"]; ok {
+			t.Errorf("unexpected reqbodylen: %v", attrs)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("expect %d files, but got %d", 2, len(files))
+	}
+
+	var bodies []string
+	for _, file := range files {
+		req, err := LoadCapturedRequest(file, "http://127.0.0.1:8080/")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if req.Method != http.MethodPost || req.URL.String() != "http://127.0.0.1:8080/path" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		if v := req.Header.Get("Authorization"); v != RedactedValue {
+			t.Errorf("expect Authorization '%s', but got '%s'", RedactedValue, v)
+		}
+
+		data, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(data))
+	}
+
+	if strings.Join(bodies, ",") != "abc,xyz" && strings.Join(bodies, ",") != "xyz,abc" {
+		t.Errorf("unexpected bodies: %v", bodies)
+	}
+
+	if _, err := LoadCapturedRequest(filepath.Join(dir, "missing.json"), ""); !os.IsNotExist(err) {
+		t.Errorf("expect a not-exist error, but got %v", err)
+	}
+}
+
+func TestCaptureDirBodyFields(t *testing.T) {
+	dir := t.TempDir()
+	_ = logCaptureDir.Set(dir)
+	_ = logBodyFields.Set([]string{"id"})
+	defer func() {
+		_ = logCaptureDir.Set("")
+		_ = logBodyFields.Set([]string{})
+		capturedfiles.Store(0)
+	}()
+
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(`{"id":1,"ssn":"123-45-6789"}`))
+	req.Header.Set("Content-Type", "application/json")
+	serveAttrs(func(w http.ResponseWriter, r *http.Request) { _, _ = io.ReadAll(r.Body) }, req)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("expect %d file, but got %d", 1, len(files))
+	}
+
+	captured, err := LoadCapturedRequest(files[0], "http://127.0.0.1:8080/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := io.ReadAll(captured.Body); string(data) != `{"id":1}` {
+		t.Errorf("expect body '%s', but got '%s'", `{"id":1}`, data)
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"strconv"
+)
+
+var errcborbreak = errors.New("unexpected cbor break")
+
+// decodecbor transcodes the CBOR body into JSON.
+//
+// The tags are ignored and only their contents are written.
+func decodecbor(_ *http.Request, _ string, data []byte) ([]byte, error) {
+	return transcodebin(data, cborvalue)
+}
+
+func cborvalue(r *binreader, buf *bytes.Buffer, depth int) (err error) {
+	if depth > maxbindepth {
+		return errbindepth
+	}
+
+	p, err := r.next(1)
+	if err != nil {
+		return
+	}
+
+	major, info := p[0]>>5, p[0]&0x1f
+	if major == 7 {
+		return cborsimple(r, buf, info)
+	} else if info == 31 {
+		return cborindefinite(r, buf, major, depth)
+	}
+
+	n, err := cborarg(r, info)
+	if err != nil {
+		return
+	}
+
+	switch major {
+	case 0: // unsigned integer
+		buf.WriteString(strconv.FormatUint(n, 10))
+
+	case 1: // negative integer
+		if n <= math.MaxInt64 {
+			buf.WriteString(strconv.FormatInt(-1-int64(n), 10))
+		} else {
+			v := new(big.Int).SetUint64(n)
+			buf.WriteString(v.Neg(v.Add(v, big.NewInt(1))).String())
+		}
+
+	case 2: // byte string
+		if p, err = r.next(n); err == nil {
+			writejsonvalue(buf, p)
+		}
+
+	case 3: // text string
+		if p, err = r.next(n); err == nil {
+			writejsonvalue(buf, string(p))
+		}
+
+	case 4: // array
+		buf.WriteByte('[')
+		for i := uint64(0); i < n && err == nil; i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			err = cborvalue(r, buf, depth+1)
+		}
+		buf.WriteByte(']')
+
+	case 5: // map
+		buf.WriteByte('{')
+		for i := uint64(0); i < n && err == nil; i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			err = cborpair(r, buf, depth)
+		}
+		buf.WriteByte('}')
+
+	case 6: // tag
+		err = cborvalue(r, buf, depth+1)
+	}
+
+	return
+}
+
+func cborarg(r *binreader, info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info <= 27:
+		return r.uint(1 << (info - 24))
+	default:
+		return 0, fmt.Errorf("invalid cbor additional info %d", info)
+	}
+}
+
+func cborpair(r *binreader, buf *bytes.Buffer, depth int) error {
+	var key bytes.Buffer
+	if err := cborvalue(r, &key, depth+1); err != nil {
+		return err
+	}
+	writejsonkey(buf, key.Bytes())
+	return cborvalue(r, buf, depth+1)
+}
+
+// cborbreak reports whether the next byte is the break stop code,
+// and skips it if so.
+func cborbreak(r *binreader) bool {
+	if len(r.data) > 0 && r.data[0] == 0xff {
+		r.data = r.data[1:]
+		return true
+	}
+	return false
+}
+
+func cborindefinite(r *binreader, buf *bytes.Buffer, major byte, depth int) (err error) {
+	switch major {
+	case 2, 3: // the chunks of the byte or text string
+		var s []byte
+		for !cborbreak(r) {
+			var p []byte
+			if p, err = r.next(1); err != nil {
+				return
+			} else if p[0]>>5 != major || p[0]&0x1f == 31 {
+				return fmt.Errorf("invalid cbor string chunk 0x%x", p[0])
+			}
+
+			var n uint64
+			if n, err = cborarg(r, p[0]&0x1f); err != nil {
+				return
+			} else if p, err = r.next(n); err != nil {
+				return
+			}
+			s = append(s, p...)
+		}
+
+		if major == 2 {
+			writejsonvalue(buf, s)
+		} else {
+			writejsonvalue(buf, string(s))
+		}
+
+	case 4:
+		buf.WriteByte('[')
+		for i := 0; err == nil && !cborbreak(r); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			err = cborvalue(r, buf, depth+1)
+		}
+		buf.WriteByte(']')
+
+	case 5:
+		buf.WriteByte('{')
+		for i := 0; err == nil && !cborbreak(r); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			err = cborpair(r, buf, depth)
+		}
+		buf.WriteByte('}')
+
+	default:
+		err = fmt.Errorf("invalid cbor indefinite length for major type %d", major)
+	}
+
+	return
+}
+
+func cborsimple(r *binreader, buf *bytes.Buffer, info byte) error {
+	switch info {
+	case 20:
+		buf.WriteString("false")
+	case 21:
+		buf.WriteString("true")
+	case 22, 23: // null, undefined
+		buf.WriteString("null")
+
+	case 24: // simple value with one byte
+		p, err := r.next(1)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(strconv.FormatUint(uint64(p[0]), 10))
+
+	case 25: // half-precision float
+		v, err := r.uint(2)
+		if err != nil {
+			return err
+		}
+		writejsonfloat(buf, halffloat(uint16(v)), 32)
+
+	case 26: // single-precision float
+		v, err := r.uint(4)
+		if err != nil {
+			return err
+		}
+		writejsonfloat(buf, float64(math.Float32frombits(uint32(v))), 32)
+
+	case 27: // double-precision float
+		v, err := r.uint(8)
+		if err != nil {
+			return err
+		}
+		writejsonfloat(buf, math.Float64frombits(v), 64)
+
+	case 31:
+		return errcborbreak
+
+	default:
+		if info > 27 {
+			return fmt.Errorf("invalid cbor additional info %d", info)
+		}
+		buf.WriteString(strconv.FormatUint(uint64(info), 10))
+	}
+
+	return nil
+}
+
+func halffloat(h uint16) (f float64) {
+	exp, frac := int(h>>10&0x1f), float64(h&0x3ff)
+	switch exp {
+	case 0:
+		f = math.Ldexp(frac, -24)
+	case 31:
+		if frac == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(frac+1024, exp-25)
+	}
+
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import "testing"
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		data   []byte
+		expect string
+	}{
+		{
+			data: []byte{0xa3, 0x61, 'a', 0x01, 0x61, 'b', 0x84, 0xf5, 0xf6, 0x20, 0x61, 'x',
+				0x61, 'c', 0xf9, 0x3e, 0x00},
+			expect: `{"a":1,"b":[true,null,-1,"x"],"c":1.5}`,
+		},
+		{data: []byte{0xa1, 0x01, 0x38, 0x63}, expect: `{"1":-100}`},
+		{data: []byte{0x19, 0x01, 0x00}, expect: `256`},
+		{data: []byte{0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, expect: `-18446744073709551616`},
+		{data: []byte{0x42, 0x01, 0x02}, expect: `"AQI="`},
+		{data: []byte{0x9f, 0x01, 0x02, 0xff}, expect: `[1,2]`},
+		{data: []byte{0xbf, 0x61, 'a', 0x01, 0xff}, expect: `{"a":1}`},
+		{data: []byte{0x7f, 0x61, 'a', 0x61, 'b', 0xff}, expect: `"ab"`},
+		{data: []byte{0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0}, expect: `1363896240`},
+		{data: []byte{0xfb, 0x3f, 0xf1, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a}, expect: `1.1`},
+		{data: []byte{0xf9, 0x7c, 0x00}, expect: `"+Inf"`},
+	}
+
+	for i, test := range tests {
+		if data, err := decodecbor(nil, "request", test.data); err != nil {
+			t.Errorf("%d: unexpected error: %v", i, err)
+		} else if string(data) != test.expect {
+			t.Errorf("%d: expect '%s', but got '%s'", i, test.expect, data)
+		}
+	}
+
+	for i, data := range [][]byte{{0x62, 'a'}, {0xff}, {0x01, 0x02}, {0x7f, 0x01, 0xff}} {
+		if _, err := decodecbor(nil, "request", data); err == nil {
+			t.Errorf("%d: expect an error, but got nil", i)
+		}
+	}
+
+	deep := make([]byte, maxbindepth+2)
+	for i := range deep {
+		deep[i] = 0x81
+	}
+	if _, err := decodecbor(nil, "request", deep); err != errbindepth {
+		t.Errorf("expect error errbindepth, but got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// getcharset returns the lower-case charset of the content type in header,
+// or "" if it is not set or is compatible with UTF-8.
+func getcharset(header http.Header) string {
+	ct := header.Get("Content-Type")
+	if !strings.Contains(ct, "charset") {
+		return ""
+	}
+
+	_, params, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return ""
+	}
+
+	switch charset := strings.ToLower(params["charset"]); charset {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return ""
+	default:
+		return charset
+	}
+}
+
+// transcodebody transcodes the body data in the charset of the content type
+// into UTF-8 to log, and returns it and the total length adjusted by
+// the difference of the transcoded data, which may be only a part of the body.
+//
+// If the charset is unknown or the transcoding fails, return data and size as-is.
+func transcodebody(header http.Header, data []byte, size int) ([]byte, int) {
+	charset := getcharset(header)
+	if charset == "" || len(data) == 0 {
+		return data, size
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return data, size
+	}
+
+	_data, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return data, size
+	}
+	return _data, size - len(data) + len(_data)
+}
+
+// validutf8 returns data whose invalid UTF-8 byte sequences are replaced
+// with the replacement character U+FFFD, or data as-is if it is valid.
+func validutf8(data []byte) []byte {
+	if utf8.Valid(data) {
+		return data
+	}
+	return bytes.ToValidUTF8(data, []byte(string(utf8.RuneError)))
+}
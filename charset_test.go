@@ -0,0 +1,62 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCharsetBody(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logRespBody.Set(true)
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logRespBody.Set(false)
+	}()
+
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("caf\xe9"))
+	req.Header.Set("Content-Type", "text/plain; charset=ISO-8859-1")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=GBK")
+		_, _ = w.Write([]byte("\xc4\xe3\xba\xc3"))
+	}, req)
+
+	if v := attrs["reqbody"].String(); v != "café" {
+		t.Errorf("expect reqbody '%s', but got '%s'", "café", v)
+	}
+	if v := attrs["reqbodylen"].Int64(); v != 4 {
+		t.Errorf("expect reqbodylen %d, but got %d", 4, v)
+	}
+	if v := attrs["respbody"].String(); v != "你好" {
+		t.Errorf("expect respbody '%s', but got '%s'", "你好", v)
+	}
+}
+
+func TestInvalidUTF8Body(t *testing.T) {
+	_ = logReqBody.Set(true)
+	defer func() { _ = logReqBody.Set(false) }()
+
+	body := "0123456789\xffabcdefghij"
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/plain")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+
+	if v := attrs["reqbody"].String(); v != "0123456789�abcdefghij" {
+		t.Errorf("unexpected reqbody '%s'", v)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// parseproxies parses the trusted proxies, which are the CIDRs or IPs.
+func parseproxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		if strings.IndexByte(proxy, '/') > -1 {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy '%s': %w", proxy, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+		} else {
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy '%s': %w", proxy, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return prefixes, nil
+}
+
+// getclientip returns the real client ip of the request.
+//
+// Only if the direct peer is a trusted proxy, the headers configured by
+// the option clientipheaders are inspected in order. For X-Forwarded-For
+// and Forwarded, the rightmost address which is not a trusted proxy is used.
+func getclientip(c *Config, r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+
+	proxies, _ := parseproxies(c.TrustedProxies)
+	if !istrusted(proxies, remote) {
+		return remote
+	}
+
+	for _, name := range c.ClientIPHeaders {
+		var addrs []string
+		switch strings.ToLower(name) {
+		case "forwarded":
+			addrs = parseforwarded(r.Header.Values(name))
+		default:
+			for _, value := range r.Header.Values(name) {
+				for _, addr := range strings.Split(value, ",") {
+					if addr = strings.TrimSpace(addr); addr != "" {
+						addrs = append(addrs, addr)
+					}
+				}
+			}
+		}
+
+		if ip := rightmostuntrusted(proxies, addrs); ip != "" {
+			return ip
+		}
+	}
+
+	return remote
+}
+
+func rightmostuntrusted(proxies []netip.Prefix, addrs []string) (ip string) {
+	for i := len(addrs) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(trimport(addrs[i]))
+		if err != nil {
+			return "" // Not trust the invalid address and the ones before it.
+		}
+
+		ip = addr.Unmap().String()
+		if !istrusted(proxies, ip) {
+			return
+		}
+	}
+	return // All are trusted proxies, so use the leftmost.
+}
+
+// parseforwarded returns the addresses of the parameter "for"
+// of the header Forwarded, see RFC 7239.
+func parseforwarded(values []string) (addrs []string) {
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, addr, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					addrs = append(addrs, strings.Trim(addr, `"`))
+				}
+			}
+		}
+	}
+	return
+}
+
+// trimport removes the port and the brackets of the IPv6 address.
+func trimport(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+func istrusted(proxies []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+	for _, proxy := range proxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
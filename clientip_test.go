@@ -0,0 +1,62 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	_ = logClientIP.Set(true)
+	_ = logTrustedProxies.Set([]string{"10.0.0.0/8", "::1"})
+	defer func() {
+		_ = logClientIP.Set(false)
+		_ = logTrustedProxies.Set([]string{})
+	}()
+
+	for _, tc := range []struct {
+		raddr  string
+		header string
+		value  string
+		expect string
+	}{
+		{"1.2.3.4:1234", "X-Forwarded-For", "5.6.7.8", "1.2.3.4"},
+		{"10.0.0.1:1234", "", "", "10.0.0.1"},
+		{"10.0.0.1:1234", "X-Forwarded-For", "5.6.7.8, 10.0.0.2", "5.6.7.8"},
+		{"10.0.0.1:1234", "X-Forwarded-For", "9.9.9.9, 5.6.7.8, 10.0.0.2", "5.6.7.8"},
+		{"10.0.0.1:1234", "X-Forwarded-For", "10.0.0.3, 10.0.0.2", "10.0.0.3"},
+		{"10.0.0.1:1234", "X-Forwarded-For", "unknown, 10.0.0.2", "10.0.0.1"},
+		{"10.0.0.1:1234", "X-Real-IP", "5.6.7.8", "5.6.7.8"},
+		{"[::1]:1234", "Forwarded", `for=192.0.2.60;proto=http, for="[2001:db8::1]:4711"`, "2001:db8::1"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/path", nil)
+		req.RemoteAddr = tc.raddr
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+
+		if v := collectAttrs(httptest.NewRecorder(), req)["client_ip"].String(); v != tc.expect {
+			t.Errorf("%s %s=%s: expect client_ip '%s', but got '%s'", tc.raddr, tc.header, tc.value, tc.expect, v)
+		}
+	}
+
+	c := EffectiveConfig()
+	c.TrustedProxies = []string{"10.0.0.0/33"}
+	if err := c.Validate(); err == nil {
+		t.Error("expect an error for the invalid trusted proxy")
+	}
+}
@@ -0,0 +1,816 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xgfone/gconf/v6"
+)
+
+// Config is the snapshot of the active settings.
+type Config struct {
+	LogQuery       bool `json:"query"`
+	LogRequestLine bool `json:"requestline"`
+	LogReqBody     bool `json:"reqbody"`
+	LogRespBody    bool `json:"respbody"`
+	LogReqHeaders  bool `json:"reqheaders"`
+	LogRespHeaders bool `json:"respheaders"`
+
+	RedactQueries []string `json:"redactqueries"`
+	RedactHeaders []string `json:"redactheaders"`
+	RedactFields  []string `json:"redactfields"`
+	RedactXML     []string `json:"redactxml"`
+
+	AuthMask      string `json:"authmask"`
+	AuthPrefixLen int    `json:"authprefixlen"`
+
+	LogCookies    bool     `json:"cookies"`
+	RedactCookies []string `json:"redactcookies"`
+	ScrubPII      []string `json:"scrubpii"`
+
+	LogClientIP     bool     `json:"clientip"`
+	ClientIPHeaders []string `json:"clientipheaders"`
+	TrustedProxies  []string `json:"trustedproxies"`
+
+	GraphQLPaths []string `json:"graphqlpaths"`
+
+	LogBasicUser bool     `json:"basicuser"`
+	JWTClaims    []string `json:"jwtclaims"`
+
+	LogTLS              bool `json:"tls"`
+	LogClientCert       bool `json:"clientcert"`
+	LogClientCertDetail bool `json:"clientcertdetail"`
+
+	LogReferer         bool `json:"referer"`
+	LogRefererPathOnly bool `json:"refererpathonly"`
+
+	LogTrailers bool `json:"trailers"`
+	LogStatus   bool `json:"status"`
+	LogCurl     bool `json:"curl"`
+
+	MutatingBodyOnly bool `json:"mutatingbodyonly"`
+
+	BodyMaxLen   int      `json:"bodymaxlen"`
+	BodyMaxLens  []string `json:"bodymaxlens"`
+	BodyBudget   int      `json:"bodybudget"`
+	BufSize      int      `json:"bufsize"`
+	BufMaxCap    int      `json:"bufmaxcap"`
+	ZeroCopy     bool     `json:"zerocopy"`
+	TruncateBody bool     `json:"truncatebody"`
+	TruncateTail int      `json:"truncatetail"`
+	LazyReqBody  bool     `json:"lazyreqbody"`
+	BodyOnError  bool     `json:"bodyonerror"`
+	BodyTypes    []string `json:"bodytypes"`
+	BodyFields   []string `json:"bodyfields"`
+	CTBodyFields []string `json:"ctbodyfields"`
+	LogXML       bool     `json:"xml"`
+	XMLCompact   bool     `json:"xmlcompact"`
+	JSONCompact  bool     `json:"jsoncompact"`
+	PrettyBody   bool     `json:"prettybody"`
+	FormBody     bool     `json:"formbody"`
+	NDJSONLines  int      `json:"ndjsonlines"`
+	StreamMaxLen int      `json:"streammaxlen"`
+
+	BodySampleRate float64       `json:"bodysamplerate"`
+	SlowThreshold  time.Duration `json:"slowthreshold"`
+	MultipartMeta  bool          `json:"multipartmeta"`
+	BodyHash       bool          `json:"bodyhash"`
+	BodyHashOnly   bool          `json:"bodyhashonly"`
+
+	BinaryTypes      []string `json:"binarytypes"`
+	BinarySnippetLen int      `json:"binarysnippetlen"`
+	BinaryEncoding   string   `json:"binaryencoding"`
+
+	IncludeTextTypes bool    `json:"includetexttypes"`
+	BinaryThreshold  float64 `json:"binarythreshold"`
+	EmptyBody        string  `json:"emptybody"`
+	StrictOrdering   bool    `json:"strictordering"`
+	PushHeader       string  `json:"pushheader"`
+	DefaultService   string  `json:"defaultservice"`
+
+	AttrOrder   []string `json:"attrorder"`
+	GroupAttrs  bool     `json:"groupattrs"`
+	EventBuffer int      `json:"eventbuffer"`
+	SizeWindow  int      `json:"sizewindow"`
+
+	FlightRecorder int `json:"flightrecorder"`
+
+	SpillDir       string        `json:"spilldir"`
+	SpillMaxLen    int           `json:"spillmaxlen"`
+	SpillRetention time.Duration `json:"spillretention"`
+
+	CaptureDir        string  `json:"capturedir"`
+	CaptureSampleRate float64 `json:"capturesamplerate"`
+	CaptureMaxFiles   int     `json:"capturemaxfiles"`
+
+	LogErrorBodies  bool `json:"logerrorbodies"`
+	ErrorBodyMaxLen int  `json:"errorbodymaxlen"`
+
+	IgnorePaths  []string `json:"ignorepaths"`
+	SkipHeaders  []string `json:"skipheaders"`
+	ForceHeaders []string `json:"forceheaders"`
+	Statuses     []string `json:"statuses"`
+
+	DebugHeader string `json:"debugheader"`
+	DebugToken  string `json:"debugtoken"`
+
+	Keys Keys `json:"keys"`
+}
+
+// EffectiveConfig returns the snapshot of the current effective configuration.
+func EffectiveConfig() Config { return loadconfig().clone() }
+
+// loadconfig loads the configuration from the options without copying
+// the slices, which must not be modified.
+func loadconfig() Config {
+	return Config{
+		LogQuery:       logQuery.Get(),
+		LogRequestLine: logRequestLine.Get(),
+		LogReqBody:     logReqBody.Get(),
+		LogRespBody:    logRespBody.Get(),
+		LogReqHeaders:  logReqHeaders.Get(),
+		LogRespHeaders: logRespHeaders.Get(),
+
+		RedactQueries: logRedactQueries.Get(),
+		RedactHeaders: logRedactHeaders.Get(),
+		RedactFields:  logRedactFields.Get(),
+		RedactXML:     logRedactXML.Get(),
+
+		AuthMask:      logAuthMask.Get(),
+		AuthPrefixLen: logAuthPrefixLen.Get(),
+
+		LogCookies:    logCookies.Get(),
+		RedactCookies: logRedactCookies.Get(),
+		ScrubPII:      logScrubPII.Get(),
+
+		LogClientIP:     logClientIP.Get(),
+		ClientIPHeaders: logClientIPHeaders.Get(),
+		TrustedProxies:  logTrustedProxies.Get(),
+
+		GraphQLPaths: logGraphQLPaths.Get(),
+
+		LogBasicUser: logBasicUser.Get(),
+		JWTClaims:    logJWTClaims.Get(),
+
+		LogTLS:              logTLS.Get(),
+		LogClientCert:       logClientCert.Get(),
+		LogClientCertDetail: logClientCertDetail.Get(),
+
+		LogReferer:         logReferer.Get(),
+		LogRefererPathOnly: logRefererPathOnly.Get(),
+
+		LogTrailers: logTrailers.Get(),
+		LogStatus:   logStatus.Get(),
+		LogCurl:     logCurl.Get(),
+
+		MutatingBodyOnly: logMutatingBodyOnly.Get(),
+
+		BodyMaxLen:   logBodyMaxLen.Get(),
+		BodyMaxLens:  logBodyMaxLens.Get(),
+		BodyBudget:   logBodyBudget.Get(),
+		BufSize:      logBufSize.Get(),
+		BufMaxCap:    logBufMaxCap.Get(),
+		ZeroCopy:     logZeroCopy.Get(),
+		TruncateBody: logTruncateBody.Get(),
+		TruncateTail: logTruncateTail.Get(),
+		LazyReqBody:  logLazyReqBody.Get(),
+		BodyOnError:  logBodyOnError.Get(),
+		BodyTypes:    logBodyTypes.Get(),
+		BodyFields:   logBodyFields.Get(),
+		CTBodyFields: logCTBodyFields.Get(),
+		LogXML:       logXML.Get(),
+		XMLCompact:   logXMLCompact.Get(),
+		JSONCompact:  logJSONCompact.Get(),
+		PrettyBody:   logPrettyBody.Get(),
+		FormBody:     logFormBody.Get(),
+		NDJSONLines:  logNDJSONLines.Get(),
+		StreamMaxLen: logStreamMaxLen.Get(),
+
+		BodySampleRate: logBodySampleRate.Get(),
+		SlowThreshold:  logSlowThreshold.Get(),
+		MultipartMeta:  logMultipartMeta.Get(),
+		BodyHash:       logBodyHash.Get(),
+		BodyHashOnly:   logBodyHashOnly.Get(),
+
+		BinaryTypes:      logBinaryTypes.Get(),
+		BinarySnippetLen: logBinarySnippetLen.Get(),
+		BinaryEncoding:   logBinaryEncoding.Get(),
+
+		IncludeTextTypes: logIncludeTextTypes.Get(),
+		BinaryThreshold:  logBinaryThreshold.Get(),
+		EmptyBody:        logEmptyBody.Get(),
+		StrictOrdering:   logStrictOrdering.Get(),
+		PushHeader:       logPushHeader.Get(),
+		DefaultService:   logDefaultService.Get(),
+
+		AttrOrder:   logAttrOrder.Get(),
+		GroupAttrs:  logGroupAttrs.Get(),
+		EventBuffer: logEventBuffer.Get(),
+		SizeWindow:  logSizeWindow.Get(),
+
+		FlightRecorder: logFlightRecorder.Get(),
+
+		SpillDir:       logSpillDir.Get(),
+		SpillMaxLen:    logSpillMaxLen.Get(),
+		SpillRetention: logSpillRetention.Get(),
+
+		CaptureDir:        logCaptureDir.Get(),
+		CaptureSampleRate: logCaptureSampleRate.Get(),
+		CaptureMaxFiles:   logCaptureMaxFiles.Get(),
+
+		LogErrorBodies:  logErrorBodies.Get(),
+		ErrorBodyMaxLen: logErrorBodyMaxLen.Get(),
+
+		IgnorePaths:  loadignorepaths(),
+		SkipHeaders:  logSkipHeaders.Get(),
+		ForceHeaders: logForceHeaders.Get(),
+		Statuses:     logStatuses.Get(),
+
+		DebugHeader: logDebugHeader.Get(),
+		DebugToken:  logDebugToken.Get(),
+
+		Keys: loadkeys(),
+	}
+}
+
+// clone returns a copy of the configuration, which copies the slices.
+func (c Config) clone() Config {
+	c.RedactQueries = slices.Clone(c.RedactQueries)
+	c.RedactHeaders = slices.Clone(c.RedactHeaders)
+	c.RedactFields = slices.Clone(c.RedactFields)
+	c.RedactXML = slices.Clone(c.RedactXML)
+	c.RedactCookies = slices.Clone(c.RedactCookies)
+	c.ScrubPII = slices.Clone(c.ScrubPII)
+	c.BodyTypes = slices.Clone(c.BodyTypes)
+	c.BodyMaxLens = slices.Clone(c.BodyMaxLens)
+	c.BodyFields = slices.Clone(c.BodyFields)
+	c.CTBodyFields = slices.Clone(c.CTBodyFields)
+	c.BinaryTypes = slices.Clone(c.BinaryTypes)
+	c.AttrOrder = slices.Clone(c.AttrOrder)
+	c.IgnorePaths = slices.Clone(c.IgnorePaths)
+	c.SkipHeaders = slices.Clone(c.SkipHeaders)
+	c.ForceHeaders = slices.Clone(c.ForceHeaders)
+	c.Statuses = slices.Clone(c.Statuses)
+	c.ClientIPHeaders = slices.Clone(c.ClientIPHeaders)
+	c.TrustedProxies = slices.Clone(c.TrustedProxies)
+	c.JWTClaims = slices.Clone(c.JWTClaims)
+	c.GraphQLPaths = slices.Clone(c.GraphQLPaths)
+	return c
+}
+
+var (
+	configkey   = contextkey{key: "configkey"}
+	resolvedkey = contextkey{key: "resolvedkey"}
+)
+
+type routeconfig struct {
+	path     string
+	override func(*Config)
+	cache    atomic.Pointer[routecache]
+}
+
+type routecache struct {
+	base   *Config
+	config *Config
+}
+
+var (
+	routelock    sync.Mutex
+	routeconfigs atomic.Pointer[[]*routeconfig]
+)
+
+// SetRouteConfig sets the override of the configuration for the requests
+// matching the route path, and the first matched route is used.
+// If the route has been set, replace it. If override is nil, remove it.
+//
+// If path ends with "/", it is a prefix matching; Or, an equal matching.
+//
+// override is applied to the copy of the configuration loaded from
+// the options, so only the overridden settings differ, for example,
+//
+//	SetRouteConfig("/api/upload", func(c *Config) { c.LogReqBody = false })
+//
+// NOTICE: the route configuration is not used by the request configured
+// by Logger.
+func SetRouteConfig(path string, override func(c *Config)) {
+	routelock.Lock()
+	defer routelock.Unlock()
+
+	var routes []*routeconfig
+	if _routes := routeconfigs.Load(); _routes != nil {
+		routes = slices.Clone(*_routes)
+	}
+
+	index := slices.IndexFunc(routes, func(r *routeconfig) bool { return r.path == path })
+	switch {
+	case override == nil && index > -1:
+		routes = slices.Delete(routes, index, index+1)
+	case override == nil:
+		return
+	case index > -1:
+		routes[index] = &routeconfig{path: path, override: override}
+	default:
+		routes = append(routes, &routeconfig{path: path, override: override})
+	}
+	routeconfigs.Store(&routes)
+}
+
+// config returns the configuration overridden by the route, which is
+// cached until the configuration loaded from the options is changed.
+func (r *routeconfig) config() *Config {
+	base := cachedconfig()
+	if cache := r.cache.Load(); cache != nil && cache.base == base {
+		return cache.config
+	}
+
+	c := base.clone()
+	r.override(&c)
+	r.cache.Store(&routecache{base: base, config: &c})
+	return &c
+}
+
+// getconfig returns the configuration of the request, which is set by
+// Logger or SetRouteConfig, or loaded from the options if not set.
+//
+// If the request carries the matched debug header, the returned
+// configuration enables the full header and body logging.
+//
+// The configuration of the request wrapped by WrapReqRespBody is resolved
+// only once, so the options updated during the request take effect
+// from the next request.
+func getconfig(r *http.Request) *Config {
+	if c, ok := r.Context().Value(resolvedkey).(*Config); ok {
+		return c // Resolved by WrapReqRespBody.
+	}
+
+	c := findconfig(r)
+	if c.isdebug(r.Header) {
+		c = c.debug()
+	}
+	return c
+}
+
+func findconfig(r *http.Request) *Config {
+	if c, ok := r.Context().Value(configkey).(*Config); ok {
+		return c
+	}
+
+	if routes := routeconfigs.Load(); routes != nil {
+		for _, route := range *routes {
+			if matchpath(route.path, r.URL.Path) {
+				return route.config()
+			}
+		}
+	}
+
+	return cachedconfig()
+}
+
+type configcache struct {
+	gen    uint64
+	config *Config
+}
+
+var (
+	configgen   atomic.Uint64
+	cachedconfs atomic.Pointer[configcache]
+)
+
+func init() {
+	gconf.Conf.Observe(func(string, interface{}, interface{}) { invalidateconfig() })
+}
+
+// invalidateconfig discards the cached configuration,
+// which is called when any option is updated.
+func invalidateconfig() { configgen.Add(1) }
+
+// cachedconfig returns the configuration loaded from the options,
+// which is cached until any option is updated and must not be modified.
+func cachedconfig() *Config {
+	gen := configgen.Load()
+	if cache := cachedconfs.Load(); cache != nil && cache.gen == gen {
+		return cache.config
+	}
+
+	// If the options are updated during loading, the generation has changed,
+	// and the configuration will be reloaded by the next call.
+	c := loadconfig()
+	cachedconfs.Store(&configcache{gen: gen, config: &c})
+	return &c
+}
+
+// isdebug reports whether the request carries the debug header
+// whose value matches the debug token.
+func (c *Config) isdebug(header http.Header) bool {
+	if c.DebugHeader == "" || c.DebugToken == "" {
+		return false
+	}
+
+	value := header.Get(c.DebugHeader)
+	return value != "" && subtle.ConstantTimeCompare([]byte(value), []byte(c.DebugToken)) == 1
+}
+
+// debug returns a copy of the configuration to log the full headers
+// and bodies regardless of the sampling and the conditions.
+func (c *Config) debug() *Config {
+	_c := *c
+	_c.LogReqHeaders, _c.LogRespHeaders = true, true
+	_c.LogReqBody, _c.LogRespBody = true, true
+	_c.MutatingBodyOnly, _c.BodyOnError = false, false
+	_c.BodySampleRate, _c.SlowThreshold = 1, 0
+	_c.BodyHashOnly, _c.Statuses = false, nil
+	return &_c
+}
+
+// forct returns the configuration for the body with the content type ct,
+// whose bodymaxlen and bodyfields are overridden by the options bodymaxlens
+// and ctbodyfields if matched.
+func (c *Config) forct(ct string) *Config {
+	maxlen, ok := c.ctbodymaxlen(ct)
+	ok = ok && maxlen > 0 && maxlen != c.BodyMaxLen
+	fields, _ok := c.ctbodyfields(ct)
+	if !ok && !_ok {
+		return c
+	}
+
+	_c := *c
+	if ok {
+		_c.BodyMaxLen = maxlen
+	}
+	if _ok {
+		_c.BodyFields = fields
+	}
+	return &_c
+}
+
+// ctbodyfields returns the fields of the JSON body with the content type ct
+// configured by the option ctbodyfields.
+//
+// If no one matches, ok is false.
+func (c *Config) ctbodyfields(ct string) (fields []string, ok bool) {
+	for _, s := range c.CTBodyFields {
+		if _ct, fields, err := parsectfields(s); err == nil && matchct(ct, []string{_ct}) {
+			return fields, true
+		}
+	}
+	return
+}
+
+// parsectfields parses the fields of the content type
+// in the format "ContentType=Field1;Field2".
+func parsectfields(s string) (ct string, fields []string, err error) {
+	ct, value, ok := strings.Cut(s, "=")
+	if ct = strings.TrimSpace(ct); !ok || ct == "" {
+		return "", nil, fmt.Errorf("invalid content type fields '%s'", s)
+	}
+
+	for _, field := range strings.Split(value, ";") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return "", nil, fmt.Errorf("missing the fields of the content type '%s'", s)
+	}
+	return
+}
+
+// ctbodymaxlen returns the maximum length of the body with the content type
+// ct configured by the option bodymaxlens, which is 0 if not logged.
+//
+// If no one matches, ok is false.
+func (c *Config) ctbodymaxlen(ct string) (maxlen int, ok bool) {
+	for _, s := range c.BodyMaxLens {
+		if _ct, maxlen, err := parsectmaxlen(s); err == nil && matchct(ct, []string{_ct}) {
+			return maxlen, true
+		}
+	}
+	return
+}
+
+// maxbodymaxlen returns the largest of bodymaxlen and bodymaxlens,
+// which is 0 if no limit.
+func (c *Config) maxbodymaxlen() int {
+	maxlen := c.BodyMaxLen
+	for _, s := range c.BodyMaxLens {
+		if _, _maxlen, err := parsectmaxlen(s); err == nil && maxlen > 0 {
+			maxlen = max(maxlen, _maxlen)
+		}
+	}
+	return maxlen
+}
+
+// parsectmaxlen parses the maximum length of the content type
+// in the format "ContentType=MaxLen".
+func parsectmaxlen(s string) (ct string, maxlen int, err error) {
+	ct, value, ok := strings.Cut(s, "=")
+	if ct = strings.TrimSpace(ct); !ok || ct == "" {
+		return "", 0, fmt.Errorf("invalid content type max length '%s'", s)
+	}
+
+	if maxlen, err = strconv.Atoi(strings.TrimSpace(value)); err != nil || maxlen < 0 {
+		return "", 0, fmt.Errorf("invalid max length of the content type '%s'", s)
+	}
+	return
+}
+
+// isignore reports whether the path is ignored by the ignore paths or patterns.
+func (c *Config) isignore(path string) bool {
+	for _, ignore := range c.IgnorePaths {
+		if matchpath(ignore, path) {
+			return true
+		}
+	}
+	for _, match := range loadignorepatterns() {
+		if match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// isskipped reports whether the request is skipped by the skip headers.
+func (c *Config) isskipped(header http.Header) bool {
+	return matchheaders(header, c.SkipHeaders)
+}
+
+// isforced reports whether the request is forced to log
+// by the force headers or the debug header.
+func (c *Config) isforced(header http.Header) bool {
+	return c.isdebug(header) || matchheaders(header, c.ForceHeaders)
+}
+
+// matchheaders reports whether the header matches any of the rules,
+// each of which is in the format "Name: value" or "Name".
+func matchheaders(header http.Header, rules []string) bool {
+	for _, rule := range rules {
+		name, value, hasvalue := strings.Cut(rule, ":")
+		v := header.Get(strings.TrimSpace(name))
+		if hasvalue {
+			if v == strings.TrimSpace(value) {
+				return true
+			}
+		} else if v != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchstatus reports whether the response status code of w matches
+// the option statuses. If the status code is unknown, it returns true.
+func (c *Config) matchstatus(w http.ResponseWriter) bool {
+	if len(c.Statuses) == 0 {
+		return true
+	}
+
+	var status int
+	if sw, ok := w.(interface{ getstatus() int }); ok {
+		status = sw.getstatus()
+	} else if rw := getResponseWriter(w); rw != nil {
+		status = rw.getstatus()
+	} else {
+		return true
+	}
+
+	if status == 0 {
+		status = http.StatusOK
+	}
+	for _, s := range c.Statuses {
+		if matchstatus(s, status) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchstatus reports whether the status code matches the pattern,
+// such as "4xx" or "404".
+func matchstatus(pattern string, status int) bool {
+	if len(pattern) == 3 && (pattern[1:] == "xx" || pattern[1:] == "XX") {
+		return status/100 == int(pattern[0]-'0')
+	}
+	code, err := strconv.Atoi(pattern)
+	return err == nil && code == status
+}
+
+// wrapenabled reports whether the request and response need to be wrapped.
+func (c *Config) wrapenabled() bool {
+	return c.LogReqBody || c.LogRespBody || c.LogErrorBodies || c.LogStatus ||
+		c.FlightRecorder > 0 || c.CaptureDir != ""
+}
+
+// Validate validates the current effective configuration,
+// which may be called at startup to fail fast.
+//
+// It is equal to EffectiveConfig().Validate().
+func Validate() error { return EffectiveConfig().Validate() }
+
+// Validate checks whether the configuration is consistent,
+// and returns the aggregated errors if not.
+func (c Config) Validate() error {
+	var errs []error
+	if c.BodyMaxLen < 0 {
+		errs = append(errs, fmt.Errorf("bodymaxlen must not be negative, but got %d", c.BodyMaxLen))
+	}
+
+	if c.ErrorBodyMaxLen < 0 {
+		errs = append(errs, fmt.Errorf("errorbodymaxlen must not be negative, but got %d", c.ErrorBodyMaxLen))
+	}
+
+	if c.BodySampleRate < 0 || c.BodySampleRate > 1 {
+		errs = append(errs, fmt.Errorf("bodysamplerate must be in [0, 1], but got %v", c.BodySampleRate))
+	}
+
+	if c.CaptureSampleRate < 0 || c.CaptureSampleRate > 1 {
+		errs = append(errs, fmt.Errorf("capturesamplerate must be in [0, 1], but got %v", c.CaptureSampleRate))
+	}
+
+	if _, err := parseproxies(c.TrustedProxies); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.TruncateTail < 0 {
+		errs = append(errs, fmt.Errorf("truncatetail must not be negative, but got %d", c.TruncateTail))
+	} else if c.TruncateTail > 0 && c.BodyMaxLen > 0 && c.TruncateTail >= c.BodyMaxLen {
+		errs = append(errs, fmt.Errorf("truncatetail must be less than bodymaxlen %d, but got %d",
+			c.BodyMaxLen, c.TruncateTail))
+	}
+
+	if c.NDJSONLines < 0 {
+		errs = append(errs, fmt.Errorf("ndjsonlines must not be negative, but got %d", c.NDJSONLines))
+	}
+
+	if c.StreamMaxLen < 0 {
+		errs = append(errs, fmt.Errorf("streammaxlen must not be negative, but got %d", c.StreamMaxLen))
+	}
+
+	if c.BodyBudget < 0 {
+		errs = append(errs, fmt.Errorf("bodybudget must not be negative, but got %d", c.BodyBudget))
+	}
+
+	if c.BufSize < 0 {
+		errs = append(errs, fmt.Errorf("bufsize must not be negative, but got %d", c.BufSize))
+	}
+
+	if c.BufMaxCap < 0 {
+		errs = append(errs, fmt.Errorf("bufmaxcap must not be negative, but got %d", c.BufMaxCap))
+	}
+
+	if c.SpillMaxLen < 0 {
+		errs = append(errs, fmt.Errorf("spillmaxlen must not be negative, but got %d", c.SpillMaxLen))
+	}
+
+	if c.SlowThreshold < 0 {
+		errs = append(errs, fmt.Errorf("slowthreshold must not be negative, but got %s", c.SlowThreshold))
+	}
+
+	if c.EventBuffer < 0 {
+		errs = append(errs, fmt.Errorf("eventbuffer must not be negative, but got %d", c.EventBuffer))
+	}
+
+	if c.BinaryThreshold < 0 || c.BinaryThreshold > 1 {
+		errs = append(errs, fmt.Errorf("binarythreshold must be in [0, 1], but got %v", c.BinaryThreshold))
+	}
+
+	switch c.EmptyBody {
+	case "omit", "empty-string", "null":
+	default:
+		errs = append(errs, fmt.Errorf("emptybody must be one of omit, empty-string and null, but got '%s'", c.EmptyBody))
+	}
+
+	for _, ct := range c.BodyTypes {
+		if err := validatect(ct); err != nil {
+			errs = append(errs, fmt.Errorf("bodytypes: %w", err))
+		}
+	}
+
+	for _, s := range c.BodyMaxLens {
+		if _, _, err := parsectmaxlen(s); err != nil {
+			errs = append(errs, fmt.Errorf("bodymaxlens: %w", err))
+		}
+	}
+
+	for _, name := range c.ScrubPII {
+		if _, ok := piipatterns[name]; !ok {
+			errs = append(errs, fmt.Errorf("scrubpii: unknown PII pattern '%s'", name))
+		}
+	}
+
+	for _, s := range c.CTBodyFields {
+		if _, _, err := parsectfields(s); err != nil {
+			errs = append(errs, fmt.Errorf("ctbodyfields: %w", err))
+		}
+	}
+
+	for _, ct := range c.BinaryTypes {
+		if err := validatect(ct); err != nil {
+			errs = append(errs, fmt.Errorf("binarytypes: %w", err))
+		}
+	}
+
+	if c.BinarySnippetLen < 0 {
+		errs = append(errs, fmt.Errorf("binarysnippetlen must not be negative, but got %d", c.BinarySnippetLen))
+	}
+
+	switch c.BinaryEncoding {
+	case "base64", "hex":
+	default:
+		errs = append(errs, fmt.Errorf("binaryencoding must be one of base64 and hex, but got '%s'", c.BinaryEncoding))
+	}
+
+	switch c.AuthMask {
+	case "redact", "drop", "scheme", "prefix", "hash":
+	default:
+		errs = append(errs, fmt.Errorf("authmask must be one of redact, drop, scheme, prefix and hash, but got '%s'", c.AuthMask))
+	}
+
+	for _, path := range c.IgnorePaths {
+		if !strings.HasPrefix(path, "/") {
+			errs = append(errs, fmt.Errorf("ignorepaths: the path '%s' does not start with '/'", path))
+		}
+	}
+
+	if c.DebugHeader != "" && c.DebugToken == "" {
+		errs = append(errs, errors.New("debugtoken must not be empty when debugheader is set"))
+	}
+
+	for _, status := range c.Statuses {
+		if !isstatuspattern(status) {
+			errs = append(errs, fmt.Errorf("statuses: invalid status pattern '%s'", status))
+		}
+	}
+
+	for _, rules := range [][]string{c.SkipHeaders, c.ForceHeaders} {
+		for _, rule := range rules {
+			if name, _, _ := strings.Cut(rule, ":"); strings.TrimSpace(name) == "" {
+				errs = append(errs, fmt.Errorf("skipheaders/forceheaders: the rule '%s' has no header name", rule))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// isstatuspattern reports whether the status pattern is valid,
+// such as "4xx" or "404".
+func isstatuspattern(pattern string) bool {
+	if len(pattern) != 3 || pattern[0] < '1' || pattern[0] > '5' {
+		return false
+	}
+	if pattern[1:] == "xx" || pattern[1:] == "XX" {
+		return true
+	}
+	code, err := strconv.Atoi(pattern)
+	return err == nil && code >= 100
+}
+
+// validatect validates the content type pattern, such as "text/plain",
+// "text/*", "*/xml" and "*+json".
+func validatect(ct string) error {
+	if ct == "" {
+		return nil
+	}
+
+	if strings.ContainsAny(ct, " \t;,") {
+		return fmt.Errorf("invalid content type pattern '%s'", ct)
+	}
+
+	if index := strings.IndexByte(ct, '*'); index > -1 {
+		if ct != "*" && index != 0 && index != len(ct)-1 {
+			return fmt.Errorf("the wildcard of the content type pattern '%s' must be at the start or end", ct)
+		}
+		if strings.Count(ct, "*") > 1 {
+			return fmt.Errorf("the content type pattern '%s' must contain only one wildcard", ct)
+		}
+		return nil
+	}
+
+	if strings.Count(ct, "/") != 1 || ct[0] == '/' || ct[len(ct)-1] == '/' {
+		return fmt.Errorf("invalid content type '%s'", ct)
+	}
+
+	return nil
+}
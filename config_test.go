@@ -0,0 +1,231 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEffectiveConfig(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logBodyMaxLen.Set(1024)
+	_ = logEmptyBody.Set("null")
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logBodyMaxLen.Set(2048)
+		_ = logEmptyBody.Set("omit")
+	}()
+	AppendIgnorePath("/effective/")
+
+	c := EffectiveConfig()
+	if !c.LogReqBody {
+		t.Errorf("expect LogReqBody true, but got false")
+	}
+	if c.LogRespBody {
+		t.Errorf("expect LogRespBody false, but got true")
+	}
+	if c.BodyMaxLen != 1024 {
+		t.Errorf("expect BodyMaxLen %d, but got %d", 1024, c.BodyMaxLen)
+	}
+	if c.EmptyBody != "null" {
+		t.Errorf("expect EmptyBody '%s', but got '%s'", "null", c.EmptyBody)
+	}
+	if !slices.Contains(c.IgnorePaths, "/effective/") {
+		t.Errorf("expect IgnorePaths to contain '%s', but got %v", "/effective/", c.IgnorePaths)
+	}
+}
+
+func TestCachedConfig(t *testing.T) {
+	c := cachedconfig()
+	if cachedconfig() != c {
+		t.Error("expect the cached configuration to be reused")
+	}
+
+	_ = logBodyMaxLen.Set(1024)
+	defer func() { _ = logBodyMaxLen.Set(2048) }()
+	if c = cachedconfig(); c.BodyMaxLen != 1024 {
+		t.Errorf("expect BodyMaxLen %d, but got %d", 1024, c.BodyMaxLen)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/path", nil)
+	w, r := WrapReqRespBody(httptest.NewRecorder(), req)
+	defer Release(w, r)
+	if getconfig(r) != getconfig(r) {
+		t.Error("expect the configuration of the wrapped request to be resolved once")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	c := EffectiveConfig()
+	c.BodyMaxLen = -1
+	c.EmptyBody = "none"
+	c.BodyTypes = []string{"text/*", "application/*/json", "text", "text/plain; charset=utf-8"}
+	c.IgnorePaths = []string{"path"}
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("expect an error, but got nil")
+	}
+
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+	if len(errs) != 6 {
+		t.Errorf("expect %d errors, but got %d: %v", 6, len(errs), err)
+	}
+}
+
+func TestSetRouteConfig(t *testing.T) {
+	_ = logReqBody.Set(true)
+	defer func() {
+		_ = logReqBody.Set(false)
+		SetRouteConfig("/api/upload", nil)
+		SetRouteConfig("/api/", nil)
+	}()
+
+	SetRouteConfig("/api/upload", func(c *Config) { c.LogReqBody = false })
+	SetRouteConfig("/api/", func(c *Config) { c.LogReqBody, c.BodyMaxLen = false, 16 })
+	SetRouteConfig("/api/", func(c *Config) { c.LogReqBody, c.BodyMaxLen = false, 32 })
+
+	newreq := func(path string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader("data"))
+		req.Header.Set("Content-Type", "text/plain")
+		return req
+	}
+
+	if c := getconfig(newreq("/api/upload")); c.LogReqBody {
+		t.Error("expect LogReqBody false for /api/upload, but got true")
+	} else if c.BodyMaxLen != 2048 || !slices.Contains(c.RedactHeaders, "Authorization") {
+		// The other settings are inherited from the options.
+		t.Errorf("unexpected config for /api/upload: %+v", c)
+	}
+
+	// The route configuration follows the updated options.
+	_ = logBodyMaxLen.Set(1024)
+	if c := getconfig(newreq("/api/upload")); c.BodyMaxLen != 1024 {
+		t.Errorf("expect BodyMaxLen %d for /api/upload, but got %d", 1024, c.BodyMaxLen)
+	}
+	_ = logBodyMaxLen.Set(2048)
+	if c := getconfig(newreq("/api/users")); c.LogReqBody || c.BodyMaxLen != 32 {
+		t.Errorf("unexpected config for /api/users: %+v", c)
+	}
+	if c := getconfig(newreq("/other")); !c.LogReqBody || c.BodyMaxLen != 2048 {
+		t.Errorf("unexpected config for /other: %+v", c)
+	}
+
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, newreq("/api/upload"))
+	if _, ok := attrs["reqbodylen"]; ok {
+		t.Error("unexpect attr reqbodylen for /api/upload")
+	}
+
+	attrs = serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, newreq("/other"))
+	if v := attrs["reqbody"].String(); v != "data" {
+		t.Errorf("expect reqbody '%s', but got '%s'", "data", v)
+	}
+}
+
+func TestSkipForceHeaders(t *testing.T) {
+	defer setignorepaths(loadignorepaths())
+	_ = logSkipHeaders.Set([]string{"X-No-Log: 1", "X-Probe"})
+	_ = logForceHeaders.Set([]string{"X-Force-Log: true"})
+	defer func() {
+		_ = logSkipHeaders.Set([]string{})
+		_ = logForceHeaders.Set([]string{})
+	}()
+	AppendIgnorePath("/ignored")
+
+	for _, tc := range []struct {
+		path    string
+		header  string
+		value   string
+		enabled bool
+	}{
+		{"/path", "", "", true},
+		{"/path", "X-No-Log", "1", false},
+		{"/path", "X-No-Log", "0", true},
+		{"/path", "X-Probe", "lb", false},
+		{"/path", "X-Force-Log", "true", true},
+		{"/ignored", "", "", false},
+		{"/ignored", "X-Force-Log", "true", true},
+		{"/ignored", "X-Force-Log", "false", false},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		if v := Enabled(req); v != tc.enabled {
+			t.Errorf("%s %s=%s: expect %v, but got %v", tc.path, tc.header, tc.value, tc.enabled, v)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/path", nil)
+	req.Header.Set("X-No-Log", "1")
+	req.Header.Set("X-Force-Log", "true")
+	if !Enabled(req) {
+		t.Error("expect the force header to override the skip header")
+	}
+
+	c := EffectiveConfig()
+	c.SkipHeaders = []string{": 1"}
+	if err := c.Validate(); err == nil {
+		t.Error("expect an error for the skip header without name")
+	}
+}
+
+func TestDebugHeader(t *testing.T) {
+	defer setignorepaths(loadignorepaths())
+	_ = logDebugHeader.Set("X-Debug-Log")
+	_ = logDebugToken.Set("secret")
+	defer func() {
+		_ = logDebugHeader.Set("")
+		_ = logDebugToken.Set("")
+	}()
+	AppendIgnorePath("/ignored")
+
+	req := httptest.NewRequest(http.MethodGet, "/ignored", nil)
+	req.Header.Set("X-Debug-Log", "wrong")
+	if Enabled(req) {
+		t.Error("expect the wrong debug token to be ignored")
+	} else if c := getconfig(req); c.LogReqBody && c.LogRespBody && c.BodySampleRate == 1 {
+		t.Error("expect no debug config for the wrong debug token")
+	}
+
+	req.Header.Set("X-Debug-Log", "secret")
+	if !Enabled(req) {
+		t.Error("expect the debug header to force the logging")
+	}
+
+	c := getconfig(req)
+	if !c.LogReqHeaders || !c.LogRespHeaders || !c.LogReqBody || !c.LogRespBody ||
+		c.BodySampleRate != 1 || c.BodyOnError || c.MutatingBodyOnly {
+		t.Errorf("unexpected debug config: %+v", c)
+	}
+
+	if h := redactheaders(c, req.Header); h.Get("X-Debug-Log") == "secret" {
+		t.Error("expect the debug token to be redacted")
+	}
+
+	_c := EffectiveConfig()
+	_c.DebugToken = ""
+	if err := _c.Validate(); err == nil {
+		t.Error("expect an error for the debug header without token")
+	}
+}
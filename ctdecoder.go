@@ -0,0 +1,151 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// ContentDecoder is used to decode the binary request or response body
+// with the content type, such as protobuf, into JSON to log.
+//
+// direction is either "request" or "response".
+type ContentDecoder func(r *http.Request, direction string, data []byte) (json []byte, err error)
+
+var ctdecoders = map[string]ContentDecoder{
+	"application/msgpack":     decodemsgpack,
+	"application/x-msgpack":   decodemsgpack,
+	"application/vnd.msgpack": decodemsgpack,
+	"application/cbor":        decodecbor,
+}
+
+// SetContentDecoder sets the decoder of the body with the content type ct,
+// such as "application/x-protobuf", whose bodies are captured and logged
+// as JSON, which is filtered and redacted like the JSON body.
+//
+// The decoders of MessagePack and CBOR are registered by default
+// for "application/msgpack", "application/x-msgpack",
+// "application/vnd.msgpack" and "application/cbor".
+//
+// If decoder is nil, unset it.
+func SetContentDecoder(ct string, decoder ContentDecoder) {
+	if decoder == nil {
+		delete(ctdecoders, ct)
+	} else {
+		ctdecoders[ct] = decoder
+	}
+}
+
+// appenddecodedbody decodes the whole body by the decoder of the content
+// type, and appends it as JSON, or the decoding error.
+//
+// If there is no decoder or the body is not whole, return false.
+func appenddecodedbody(c *Config, appendAttr func(...slog.Attr), r *http.Request,
+	direction, key, ct string, data []byte, size int) bool {
+	if len(data) != size || (c.BodyMaxLen > 0 && size > c.BodyMaxLen) {
+		return false
+	}
+
+	decoder, ok := ctdecoders[ct]
+	if !ok {
+		return false
+	}
+
+	json, err := decoder(r, direction, data)
+	if err != nil {
+		appendAttr(slog.String(key+"decodeerr", err.Error()))
+		return true
+	}
+
+	if attr, ok := getbodyattr(c, json, key, "application/json"); ok {
+		appendAttr(attr)
+	}
+	return true
+}
+
+// maxbindepth is the maximum nesting depth of the binary bodies,
+// such as MessagePack and CBOR, transcoded into JSON.
+const maxbindepth = 100
+
+var (
+	errbindepth   = errors.New("exceeded the max nesting depth")
+	errbintrailer = errors.New("unexpected trailing data")
+)
+
+// binreader is used to read the binary body to transcode it into JSON.
+type binreader struct{ data []byte }
+
+func (r *binreader) next(n uint64) ([]byte, error) {
+	if n > uint64(len(r.data)) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	p := r.data[:n]
+	r.data = r.data[n:]
+	return p, nil
+}
+
+// uint reads the big-endian unsigned integer with n bytes.
+func (r *binreader) uint(n int) (uint64, error) {
+	p, err := r.next(uint64(n))
+	return bigendian(p), err
+}
+
+func bigendian(p []byte) (v uint64) {
+	for _, b := range p {
+		v = v<<8 | uint64(b)
+	}
+	return
+}
+
+func transcodebin(data []byte, decode func(*binreader, *bytes.Buffer, int) error) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(data) * 2)
+
+	r := binreader{data: data}
+	if err := decode(&r, &buf, 0); err != nil {
+		return nil, err
+	} else if len(r.data) > 0 {
+		return nil, errbintrailer
+	}
+	return buf.Bytes(), nil
+}
+
+// writejsonkey writes the JSON value encoded from the map key as the object key,
+// which quotes it if it is not a string, such as a number.
+func writejsonkey(buf *bytes.Buffer, key []byte) {
+	if len(key) > 0 && key[0] == '"' {
+		buf.Write(key)
+	} else {
+		writejsonvalue(buf, string(key))
+	}
+	buf.WriteByte(':')
+}
+
+// writejsonfloat writes the float, and quotes NaN and ±Inf unsupported by JSON.
+func writejsonfloat(buf *bytes.Buffer, f float64, bitsize int) {
+	s := strconv.FormatFloat(f, 'g', -1, bitsize)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		writejsonvalue(buf, s)
+	} else {
+		buf.WriteString(s)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContentDecoder(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logRespBody.Set(true)
+	_ = logRedactFields.Set([]string{"secret"})
+	SetContentDecoder("application/x-test", func(r *http.Request, direction string, data []byte) ([]byte, error) {
+		if string(data) == "invalid" {
+			return nil, errors.New("invalid")
+		}
+		return json.Marshal(map[string]string{"direction": direction, "data": string(data), "secret": "x"})
+	})
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logRespBody.Set(false)
+		_ = logRedactFields.Set([]string{})
+		SetContentDecoder("application/x-test", nil)
+	}()
+
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("abc"))
+	req.Header.Set("Content-Type", "application/x-test")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-test")
+		_, _ = w.Write([]byte("invalid"))
+	}, req)
+
+	if v, ok := attrs["reqbody"].Any().(json.Marshaler); !ok {
+		t.Errorf("expect a raw json reqbody, but got '%v'", attrs["reqbody"])
+	} else if data, _ := v.MarshalJSON(); string(data) != `{"data":"abc","direction":"request","secret":"***"}` {
+		t.Errorf("unexpected reqbody '%s'", data)
+	}
+
+	// The body failing to be decoded is not logged.
+	if _, ok := attrs["respbody"]; ok {
+		t.Errorf("unexpected respbody: %v", attrs["respbody"])
+	}
+	if v := attrs["respbodydecodeerr"].String(); v != "invalid" {
+		t.Errorf("expect respbodydecodeerr '%s', but got '%s'", "invalid", v)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// curlcommand renders the request as a curl command, whose headers, query
+// and body are redacted and filtered like the logs.
+//
+// The body is only rendered when the whole body has been captured
+// for logging and it is the uncompressed text.
+func curlcommand(c *Config, r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	var b strings.Builder
+	b.WriteString("curl")
+	if r.Method != http.MethodGet {
+		b.WriteString(" -X ")
+		b.WriteString(r.Method)
+	}
+
+	header := redactheaders(c, r.Header)
+	names := make([]string, 0, len(header))
+	for name := range header {
+		if !strings.EqualFold(name, "Content-Length") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, value := range header[name] {
+			b.WriteString(" -H ")
+			b.WriteString(shellquote(name + ": " + value))
+		}
+	}
+
+	if body, ok := getcurlbody(c, r); ok {
+		b.WriteString(" --data-binary ")
+		b.WriteString(shellquote(body))
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(shellquote(scheme + "://" + r.Host + getrequesturi(c, r)))
+	return b.String()
+}
+
+func getcurlbody(c *Config, r *http.Request) (body string, ok bool) {
+	if only, _ := r.Context().Value(recordonlykey).(recordonly); only.req {
+		return
+	}
+
+	reqbody, _ := r.Context().Value(reqbodykey).(*reqbody)
+	if reqbody == nil || !reqbody.whole(c.BodyMaxLen) || reqbody.encoding != "" || reqbody.multipart != nil ||
+		len(reqbody.data) == 0 || !utf8.Valid(reqbody.data) {
+		return
+	}
+
+	data, ok := redactbody(c, reqbody.data, reqbody.ct)
+	return string(data), ok
+}
+
+// shellquote quotes s with the single quotes for the POSIX shell.
+func shellquote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCurlCommand(t *testing.T) {
+	_ = logCurl.Set(true)
+	_ = logReqBody.Set(true)
+	_ = logRedactFields.Set([]string{"password"})
+	defer func() {
+		_ = logCurl.Set(false)
+		_ = logReqBody.Set(false)
+		_ = logRedactFields.Set([]string{})
+	}()
+
+	body := `{"user":"it's me","password":"secret"}`
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer token")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+
+	expect := `curl -X POST -H 'Authorization: ***' -H 'Content-Type: application/json'` +
+		` --data-binary '{"user":"it'\''s me","password":"***"}' 'http://example.com/path'`
+	if v := attrs["curl"].String(); v != expect {
+		t.Errorf("expect curl '%s', but got '%s'", expect, v)
+	}
+
+	_ = logReqBody.Set(false)
+	req = httptest.NewRequest(http.MethodGet, "/path", nil)
+	attrs = serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+	if v := attrs["curl"].String(); v != "curl 'http://example.com/path'" {
+		t.Errorf("unexpected curl '%s'", v)
+	}
+}
+
+func TestCurlCommandBodyFields(t *testing.T) {
+	_ = logCurl.Set(true)
+	_ = logReqBody.Set(true)
+	_ = logBodyFields.Set([]string{"user"})
+	defer func() {
+		_ = logCurl.Set(false)
+		_ = logReqBody.Set(false)
+		_ = logBodyFields.Set([]string{})
+	}()
+
+	for _, v := range []struct {
+		body   string
+		expect string
+	}{
+		{`{"user":"abc","ssn":"123"}`, `curl -X POST -H 'Content-Type: application/json'` +
+			` --data-binary '{"user":"abc"}' 'http://example.com/path'`},
+
+		// The body that cannot be filtered is not rendered.
+		{`{"user":"abc","ssn"`, `curl -X POST -H 'Content-Type: application/json' 'http://example.com/path'`},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(v.body))
+		req.Header.Set("Content-Type", "application/json")
+		attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+		if curl := attrs["curl"].String(); curl != v.expect {
+			t.Errorf("expect curl '%s', but got '%s'", v.expect, curl)
+		}
+	}
+}
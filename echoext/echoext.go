@@ -0,0 +1,54 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package echoext provides the echo middleware based on
+// "github.com/xgfone/go-apiserver-middleware-logger-ext".
+package echoext
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	loggerext "github.com/xgfone/go-apiserver-middleware-logger-ext"
+)
+
+// EchoMiddleware returns an echo middleware, which wraps the request
+// and response, and emits an access log record by logger
+// like loggerext.Middleware.
+//
+// The error returned by the handler is handled by the http error handler
+// of echo in the middleware, so that the error response can be logged.
+//
+// If logger is nil, use slog.Default() instead.
+func EchoMiddleware(logger *slog.Logger) echo.MiddlewareFunc {
+	middleware := loggerext.Middleware(logger)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if route := c.Path(); route != "" {
+				req = req.WithContext(loggerext.WithRoute(req.Context(), route))
+			}
+
+			middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c.SetRequest(r)
+				c.SetResponse(echo.NewResponse(w, c.Echo()))
+				if err := next(c); err != nil {
+					c.Error(err)
+				}
+			})).ServeHTTP(c.Response(), req)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echoext
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestEchoMiddleware(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	router := echo.New()
+	router.Use(EchoMiddleware(slog.New(slog.NewTextHandler(buf, nil))))
+	router.GET("/path/:id", func(c echo.Context) error { return echo.ErrNotFound })
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/path/1", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expect the status code %d, but got %d", http.StatusNotFound, rec.Code)
+	}
+
+	if log := buf.String(); !strings.Contains(log, "status=404") || !strings.Contains(log, "path=/path/1") ||
+		!strings.Contains(log, "route=/path/:id") {
+		t.Errorf("unexpected log record: %s", log)
+	}
+}
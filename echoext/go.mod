@@ -0,0 +1,27 @@
+module github.com/xgfone/go-apiserver-middleware-logger-ext/echoext
+
+require (
+	github.com/labstack/echo/v4 v4.12.0
+	github.com/xgfone/go-apiserver-middleware-logger-ext v0.0.0
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.1 // indirect
+	github.com/labstack/gommon v0.4.2 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasttemplate v1.2.2 // indirect
+	github.com/xgfone/gconf/v6 v6.5.0 // indirect
+	github.com/xgfone/go-cast v0.8.1 // indirect
+	github.com/xgfone/go-defaults v0.13.0 // indirect
+	github.com/xgfone/go-rawjson v0.1.0 // indirect
+	golang.org/x/crypto v0.22.0 // indirect
+	golang.org/x/net v0.24.0 // indirect
+	golang.org/x/sys v0.19.0 // indirect
+	golang.org/x/text v0.15.0 // indirect
+)
+
+replace github.com/xgfone/go-apiserver-middleware-logger-ext => ../
+
+go 1.21
@@ -0,0 +1,99 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+var (
+	errUnsupportedEncoding = errors.New("unsupported content encoding")
+	errDecodedTooLarge     = errors.New("decompressed body is too large")
+)
+
+// maxDecodeRatio is the maximum ratio of the decompressed length
+// to the compressed length, which is used to avoid the decompression bomb.
+const maxDecodeRatio = 100
+
+var bodydecoders = map[string]func(io.Reader) (io.ReadCloser, error){
+	"gzip":    func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	"x-gzip":  func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	"deflate": zlib.NewReader,
+	"br":      func(r io.Reader) (io.ReadCloser, error) { return io.NopCloser(brotli.NewReader(r)), nil },
+}
+
+// SetBodyDecoder sets the decoder to decompress the request or response body
+// with the content encoding for logging, such as "zstd".
+//
+// The decoders of gzip, deflate and br have been set by default.
+func SetBodyDecoder(encoding string, decoder func(io.Reader) (io.ReadCloser, error)) {
+	bodydecoders[strings.ToLower(encoding)] = decoder
+}
+
+// decodebody decompresses the data compressed by encoding into buf,
+// and returns the total length of the decompressed data.
+//
+// If limit is greater than 0, only write at most limit bytes into buf.
+// If the decompressed data is larger than maxDecodeRatio times of data,
+// return errDecodedTooLarge.
+func decodebody(buf *bytes.Buffer, data []byte, encoding string, limit int) (size int, err error) {
+	decoder, ok := bodydecoders[strings.ToLower(strings.TrimSpace(encoding))]
+	if !ok {
+		return 0, fmt.Errorf("%w '%s'", errUnsupportedEncoding, encoding)
+	}
+
+	r, err := decoder(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	maxsize := int64(max(len(data)*maxDecodeRatio, limit))
+	w := limitWriter{buf: buf, limit: limit}
+	if _, err = io.Copy(&w, io.LimitReader(r, maxsize+1)); err == nil && int64(w.size) > maxsize {
+		err = errDecodedTooLarge
+	}
+	return w.size, err
+}
+
+// limitWriter writes at most limit bytes into buf, and counts all the bytes.
+type limitWriter struct {
+	buf   *bytes.Buffer
+	limit int
+	size  int
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	w.size += n
+
+	if w.limit > 0 {
+		if left := w.limit - w.buf.Len(); left <= 0 {
+			return n, nil
+		} else if len(p) > left {
+			p = p[:left]
+		}
+	}
+	w.buf.Write(p)
+	return n, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestDecompressedRequestBody(t *testing.T) {
+	_ = logReqBody.Set(true)
+	defer func() { _ = logReqBody.Set(false) }()
+
+	body := strings.Repeat("abc", 100)
+	buf := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(buf)
+	_, _ = io.WriteString(gw, body)
+	_ = gw.Close()
+	compressedlen := buf.Len()
+
+	req := httptest.NewRequest(http.MethodPost, "/path", buf)
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Content-Encoding", "gzip")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+	}, req)
+
+	if v := attrs["reqbodylen"].Int64(); v != int64(compressedlen) {
+		t.Errorf("expect reqbodylen %d, but got %d", compressedlen, v)
+	}
+	if v := attrs["reqbodydecompressedlen"].Int64(); v != int64(len(body)) {
+		t.Errorf("expect reqbodydecompressedlen %d, but got %d", len(body), v)
+	}
+	if v := attrs["reqbody"].String(); v != body {
+		t.Errorf("expect reqbody '%s', but got '%s'", body, v)
+	}
+}
+
+func TestDecompressedResponseBody(t *testing.T) {
+	_ = logRespBody.Set(true)
+	defer func() { _ = logRespBody.Set(false) }()
+
+	body := strings.Repeat("abc", 100)
+	encoders := map[string]func(io.Writer) io.WriteCloser{
+		"gzip": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"br":   func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+	}
+
+	for encoding, newWriter := range encoders {
+		buf := bytes.NewBuffer(nil)
+		cw := newWriter(buf)
+		_, _ = io.WriteString(cw, body)
+		_ = cw.Close()
+		compressed := buf.Bytes()
+
+		attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", encoding)
+			_, _ = w.Write(compressed)
+		}, httptest.NewRequest(http.MethodGet, "/path", nil))
+
+		if v := attrs["respbodylen"].Int64(); v != int64(len(compressed)) {
+			t.Errorf("%s: expect respbodylen %d, but got %d", encoding, len(compressed), v)
+		}
+		if v := attrs["respbodydecompressedlen"].Int64(); v != int64(len(body)) {
+			t.Errorf("%s: expect respbodydecompressedlen %d, but got %d", encoding, len(body), v)
+		}
+		if v := attrs["respbody"].String(); v != body {
+			t.Errorf("%s: expect respbody '%s', but got '%s'", encoding, body, v)
+		}
+	}
+}
+
+func TestDecodeBodyBomb(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(buf)
+	_, _ = gw.Write(make([]byte, 1<<20))
+	_ = gw.Close()
+
+	if _, err := decodebody(bytes.NewBuffer(nil), buf.Bytes(), "gzip", 100); !errors.Is(err, errDecodedTooLarge) {
+		t.Errorf("expect the error errDecodedTooLarge, but got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is the prefix of the environment variables used by ConfigFromEnv.
+const EnvPrefix = "LOGEXT_"
+
+// ConfigFromEnv returns the configuration based on DefaultConfig,
+// which is overridden by the environment variables named EnvPrefix
+// and the upper-case option names, such as LOGEXT_REQBODY=true
+// and LOGEXT_BODYMAXLEN=4096, without gconf.
+//
+// The slice value is separated by the comma, such as
+// LOGEXT_BODYTYPES=text/*,application/json, and the duration value
+// is parsed by time.ParseDuration, such as LOGEXT_SLOWTHRESHOLD=1s.
+//
+// The returned configuration is validated, which can be used by New
+// with WithConfig, for example,
+//
+//	c, err := loggerext.ConfigFromEnv()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	logger := loggerext.New(loggerext.WithConfig(func(_c *loggerext.Config) { *_c = c }))
+func ConfigFromEnv() (c Config, err error) {
+	c = DefaultConfig()
+	if err = loadenv(&c); err == nil {
+		err = c.Validate()
+	}
+	return
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func loadenv(c *Config) error {
+	var errs []error
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		key := EnvPrefix + strings.ToUpper(name)
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+
+		if err := setenvfield(v.Field(i), strings.TrimSpace(value)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func setenvfield(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+
+	case reflect.Int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(i))
+
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+
+	case reflect.String:
+		field.SetString(value)
+
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", field.Type())
+		}
+
+		var values []string
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
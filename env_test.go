@@ -0,0 +1,53 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("LOGEXT_REQBODY", "true")
+	t.Setenv("LOGEXT_BODYMAXLEN", "4096")
+	t.Setenv("LOGEXT_BODYTYPES", "text/*, application/json")
+	t.Setenv("LOGEXT_SLOWTHRESHOLD", "1s")
+	t.Setenv("LOGEXT_BODYSAMPLERATE", "0.5")
+
+	c, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.LogReqBody || c.BodyMaxLen != 4096 || c.SlowThreshold != time.Second || c.BodySampleRate != 0.5 {
+		t.Errorf("unexpected config %+v", c)
+	}
+	if !slices.Equal(c.BodyTypes, []string{"text/*", "application/json"}) {
+		t.Errorf("unexpected bodytypes %v", c.BodyTypes)
+	}
+	if c.LogRespBody != DefaultConfig().LogRespBody {
+		t.Error("the absent option is changed")
+	}
+
+	t.Setenv("LOGEXT_BODYMAXLEN", "abc")
+	if _, err = ConfigFromEnv(); err == nil {
+		t.Error("expect an error for the invalid bodymaxlen")
+	}
+
+	t.Setenv("LOGEXT_BODYMAXLEN", "-1")
+	if _, err = ConfigFromEnv(); err == nil {
+		t.Error("expect an error for the negative bodymaxlen")
+	}
+}
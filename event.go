@@ -0,0 +1,113 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BodyEvent is the event of a captured request and response,
+// which is published to the subscribers when collecting the request.
+type BodyEvent struct {
+	Time     time.Time
+	Method   string
+	Path     string
+	Status   int    // 0 if the response is not wrapped.
+	ReqBody  []byte // nil if the request body is not buffered or cannot be redacted.
+	RespBody []byte // nil if the response body is not buffered or cannot be redacted.
+}
+
+var (
+	sublock     sync.RWMutex
+	subscribers []chan BodyEvent
+	subscribed  atomic.Bool
+	dropped     atomic.Uint64
+)
+
+// Subscribe returns a bounded channel to receive the events of the captured
+// requests, which is separate from the log pipeline, such as for live tail.
+//
+// If the channel is full, the event is dropped and counted,
+// see DroppedEvents.
+func Subscribe() <-chan BodyEvent {
+	ch := make(chan BodyEvent, logEventBuffer.Get())
+
+	sublock.Lock()
+	subscribers = append(subscribers, ch)
+	subscribed.Store(true)
+	sublock.Unlock()
+
+	return ch
+}
+
+// Unsubscribe unsubscribes and closes the channel returned by Subscribe.
+func Unsubscribe(ch <-chan BodyEvent) {
+	sublock.Lock()
+	defer sublock.Unlock()
+
+	for i, c := range subscribers {
+		if c == ch {
+			subscribers = append(subscribers[:i], subscribers[i+1:]...)
+			subscribed.Store(len(subscribers) > 0)
+			close(c)
+			return
+		}
+	}
+}
+
+// DroppedEvents returns the number of the events dropped
+// because the channels of the subscribers are full.
+func DroppedEvents() uint64 { return dropped.Load() }
+
+// publish publishes the event of the request to the subscribers.
+func publish(c *Config, w http.ResponseWriter, r *http.Request) {
+	if !subscribed.Load() {
+		return
+	}
+
+	event := BodyEvent{Time: time.Now(), Method: r.Method, Path: r.URL.Path}
+	if reqbody, ok := r.Context().Value(reqbodykey).(*reqbody); ok && reqbody.buf != nil {
+		event.ReqBody = geteventbody(c, reqbody.data, reqbody.ct)
+	}
+	if rw := getResponseWriter(w); rw != nil {
+		event.Status = rw.getstatus()
+		if data, _, ok := rw.snapshot(); ok {
+			event.RespBody = geteventbody(c, data, getContentType(w.Header()))
+		}
+	}
+
+	sublock.RLock()
+	defer sublock.RUnlock()
+	for _, ch := range subscribers {
+		select {
+		case ch <- event:
+		default:
+			dropped.Add(1)
+		}
+	}
+}
+
+// geteventbody returns a copy of the body redacted and filtered like the logs,
+// which is nil if the body cannot be redacted.
+func geteventbody(c *Config, data []byte, ct string) []byte {
+	if data, ok := redactbody(c, data, ct); ok {
+		return bytes.Clone(data)
+	}
+	return nil
+}
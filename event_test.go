@@ -0,0 +1,100 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logRespBody.Set(true)
+	_ = logEventBuffer.Set(1)
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logRespBody.Set(false)
+		_ = logEventBuffer.Set(100)
+	}()
+
+	ch := Subscribe()
+	defer Unsubscribe(ch)
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, "xyz")
+	}
+
+	newreq := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("abc"))
+		req.Header.Set("Content-Type", "text/plain")
+		return req
+	}
+
+	serveAttrs(handler, newreq())
+	event := <-ch
+	if event.Method != http.MethodPost || event.Path != "/path" || event.Status != http.StatusCreated {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if string(event.ReqBody) != "abc" {
+		t.Errorf("expect the request body '%s', but got '%s'", "abc", event.ReqBody)
+	}
+	if string(event.RespBody) != "xyz" {
+		t.Errorf("expect the response body '%s', but got '%s'", "xyz", event.RespBody)
+	}
+
+	dropped := DroppedEvents()
+	serveAttrs(handler, newreq())
+	serveAttrs(handler, newreq()) // The channel is full.
+	if n := DroppedEvents() - dropped; n != 1 {
+		t.Errorf("expect %d dropped event, but got %d", 1, n)
+	}
+}
+
+func TestSubscribeRedacted(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logRespBody.Set(true)
+	_ = logBodyFields.Set([]string{"name", "password"})
+	_ = logRedactFields.Set([]string{"password"})
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logRespBody.Set(false)
+		_ = logBodyFields.Set([]string{})
+		_ = logRedactFields.Set([]string{})
+	}()
+
+	ch := Subscribe()
+	defer Unsubscribe(ch)
+
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(`{"name":"abc","password":"123","ssn":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"name":"abc","pass`)
+	}, req)
+
+	event := <-ch
+	if expect := `{"name":"abc","password":"***"}`; string(event.ReqBody) != expect {
+		t.Errorf("expect the request body '%s', but got '%s'", expect, event.ReqBody)
+	}
+	if event.RespBody != nil {
+		t.Errorf("expect no response body, but got '%s'", event.RespBody)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// FlightRecord is the request and response kept by the flight recorder.
+type FlightRecord struct {
+	Time        time.Time     `json:"time"`
+	Duration    time.Duration `json:"duration,omitempty"`
+	Method      string        `json:"method"`
+	Scheme      string        `json:"scheme,omitempty"`
+	Host        string        `json:"host,omitempty"`
+	URI         string        `json:"uri"`
+	Proto       string        `json:"proto,omitempty"`
+	Status      int           `json:"status,omitempty"`
+	ReqHeaders  http.Header   `json:"reqheaders,omitempty"`
+	RespHeaders http.Header   `json:"respheaders,omitempty"`
+	ReqBody     string        `json:"reqbody,omitempty"`
+	RespBody    string        `json:"respbody,omitempty"`
+}
+
+type recordonly struct{ req, resp bool }
+
+var recordonlykey = contextkey{key: "recordonlykey"}
+
+var flightrecords flightRing
+
+// DumpHandler returns a http handler to dump the latest requests
+// and responses kept by the flight recorder as a JSON array,
+// whose size is configured by the option flightrecorder.
+//
+// The headers and the JSON bodies are redacted like the logs.
+func DumpHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(flightrecords.snapshot())
+	})
+}
+
+// record records the request and response into the flight recorder.
+func record(c *Config, w http.ResponseWriter, r *http.Request) {
+	if c.FlightRecorder <= 0 {
+		return
+	}
+
+	rec := FlightRecord{
+		Time:       time.Now(),
+		Method:     r.Method,
+		Scheme:     "http",
+		Host:       r.Host,
+		URI:        getrequesturi(c, r),
+		Proto:      r.Proto,
+		ReqHeaders: redactheaders(c, r.Header).Clone(),
+	}
+	if r.TLS != nil {
+		rec.Scheme = "https"
+	}
+	if start, ok := r.Context().Value(startkey).(time.Time); ok {
+		rec.Duration = rec.Time.Sub(start)
+		rec.Time = start
+	}
+
+	if reqbody, ok := r.Context().Value(reqbodykey).(*reqbody); ok && reqbody.buf != nil {
+		rec.ReqBody = getrecordbody(c, reqbody.data, reqbody.ct)
+	}
+
+	if rw := getResponseWriter(w); rw != nil {
+		rec.Status = rw.getstatus()
+		rec.RespHeaders = redactheaders(c, w.Header()).Clone()
+		if data, _, ok := rw.snapshot(); ok {
+			rec.RespBody = getrecordbody(c, data, getContentType(w.Header()))
+		}
+	}
+
+	flightrecords.add(rec, c.FlightRecorder)
+}
+
+// getrecordbody returns the copy of the body, which is truncated
+// to bodymaxlen, and redacted by redactbody.
+func getrecordbody(c *Config, data []byte, ct string) string {
+	if maxlen := c.BodyMaxLen; maxlen > 0 && len(data) > maxlen {
+		data = data[:maxlen]
+	}
+
+	data, ok := redactbody(c, data, ct)
+	if !ok {
+		return "" // Not leak the fields of the invalid or truncated body.
+	}
+	return string(data)
+}
+
+// flightRing is a ring buffer of the latest flight records.
+type flightRing struct {
+	lock    sync.Mutex
+	records []FlightRecord
+	next    int
+}
+
+func (f *flightRing) add(rec FlightRecord, size int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if len(f.records) != size && f.next > 0 {
+		// The ring is resized, so reorder the records from the oldest.
+		f.records = append(slices.Clone(f.records[f.next:]), f.records[:f.next]...)
+		f.next = 0
+	}
+
+	switch {
+	case len(f.records) < size:
+		f.records = append(f.records, rec)
+
+	case len(f.records) > size:
+		f.records = append(slices.Clone(f.records[len(f.records)-size+1:]), rec)
+
+	default:
+		f.records[f.next] = rec
+		f.next = (f.next + 1) % size
+	}
+}
+
+// snapshot returns the copy of the records from the oldest to the newest.
+func (f *flightRing) snapshot() []FlightRecord {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	records := make([]FlightRecord, 0, len(f.records))
+	records = append(records, f.records[f.next:]...)
+	return append(records, f.records[:f.next]...)
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDumpHandler(t *testing.T) {
+	_ = logFlightRecorder.Set(2)
+	defer func() {
+		_ = logFlightRecorder.Set(0)
+		flightrecords = flightRing{}
+	}()
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/path%d", i), strings.NewReader("abc"))
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("Authorization", "Bearer token")
+		attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("xyz"))
+		}, req)
+
+		// The bodies are captured only for the flight recorder, but not logged.
+		if _, ok := attrs["reqbodylen"]; ok {
+			t.Errorf("unexpected reqbodylen: %v", attrs)
+		}
+		if _, ok := attrs["respbodylen"]; ok {
+			t.Errorf("unexpected respbodylen: %v", attrs)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	DumpHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dump", nil))
+
+	var records []FlightRecord
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expect %d records, but got %d", 2, len(records))
+	}
+
+	for i, r := range records {
+		if uri := fmt.Sprintf("/path%d", i+1); r.URI != uri {
+			t.Errorf("expect uri '%s', but got '%s'", uri, r.URI)
+		}
+		if r.Status != 200 || r.ReqBody != "abc" || r.RespBody != "xyz" {
+			t.Errorf("unexpected record %+v", r)
+		}
+		if v := r.ReqHeaders.Get("Authorization"); v != RedactedValue {
+			t.Errorf("expect Authorization '%s', but got '%s'", RedactedValue, v)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	DumpHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/dump", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expect status %d, but got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
+
+func TestFlightRingResize(t *testing.T) {
+	var ring flightRing
+	for i := 0; i < 5; i++ {
+		ring.add(FlightRecord{Status: i}, 3)
+	}
+	ring.add(FlightRecord{Status: 5}, 2)
+	ring.add(FlightRecord{Status: 6}, 4)
+
+	var statuses []int
+	for _, r := range ring.snapshot() {
+		statuses = append(statuses, r.Status)
+	}
+	if fmt.Sprint(statuses) != "[4 5 6]" {
+		t.Errorf("unexpected records %v", statuses)
+	}
+}
+
+func TestDumpHandlerScrubPII(t *testing.T) {
+	_ = logFlightRecorder.Set(1)
+	_ = logScrubPII.Set([]string{"email"})
+	defer func() {
+		_ = logFlightRecorder.Set(0)
+		_ = logScrubPII.Set([]string{})
+		flightrecords = flightRing{}
+	}()
+
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(`{"email":"a@b.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("From", "a@b.com")
+	serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("contact a@b.com"))
+	}, req)
+
+	records := flightrecords.snapshot()
+	if len(records) != 1 {
+		t.Fatalf("expect %d record, but got %d", 1, len(records))
+	}
+
+	r := records[0]
+	if r.ReqBody != `{"email":"***"}` || r.RespBody != "contact ***" || r.ReqHeaders.Get("From") != RedactedValue {
+		t.Errorf("unexpected record %+v", r)
+	}
+}
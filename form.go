@@ -0,0 +1,73 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"log/slog"
+	"net/url"
+	"sort"
+)
+
+// isformct reports whether ct is the content type of the url-encoded form.
+func isformct(ct string) bool {
+	return ct == "application/x-www-form-urlencoded"
+}
+
+// getformattr parses the url-encoded form body and returns it as a group
+// of the fields sorted by the key, whose values of the keys configured
+// by the option redactqueries are redacted by the query redactor.
+//
+// The field with multiple values is logged as a string slice.
+func getformattr(c *Config, data []byte, key string) (attr slog.Attr, err error) {
+	// Copy the body since the parsed values may reference it.
+	form, err := url.ParseQuery(string(data))
+	if err != nil {
+		return
+	}
+
+	keys := make([]string, 0, len(form))
+	for k := range form {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	redactform(c, form)
+	attrs := make([]any, 0, len(keys))
+	for _, k := range keys {
+		if values := form[k]; len(values) == 1 {
+			attrs = append(attrs, slog.String(k, values[0]))
+		} else {
+			attrs = append(attrs, slog.Any(k, values))
+		}
+	}
+
+	return slog.Group(key, attrs...), nil
+}
+
+// redactform redacts the values of the form in place by the query redactor
+// for the keys configured by the option redactqueries, or scrubs their PII.
+func redactform(c *Config, form url.Values) {
+	for k, values := range form {
+		if containsfold(c.RedactQueries, k) {
+			for i, value := range values {
+				values[i] = queryRedactor(k, value)
+			}
+		} else if len(c.ScrubPII) > 0 {
+			for i, value := range values {
+				values[i] = scrubpii(c, value)
+			}
+		}
+	}
+}
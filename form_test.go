@@ -0,0 +1,63 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFormBody(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logFormBody.Set(true)
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logFormBody.Set(false)
+	}()
+
+	body := "user=it%27s+me&password=secret&tag=a&tag=b"
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+
+	if v := attrs["reqbody"]; v.Kind() != slog.KindGroup {
+		t.Fatalf("expect a group reqbody, but got '%v'", v)
+	}
+
+	fields := make(map[string]slog.Value)
+	for _, attr := range attrs["reqbody"].Group() {
+		fields[attr.Key] = attr.Value
+	}
+	if v := fields["user"].String(); v != "it's me" {
+		t.Errorf("expect user '%s', but got '%s'", "it's me", v)
+	}
+	if v := fields["password"].String(); v != RedactedValue {
+		t.Errorf("expect password '%s', but got '%s'", RedactedValue, v)
+	}
+	if v, _ := fields["tag"].Any().([]string); len(v) != 2 || v[0] != "a" || v[1] != "b" {
+		t.Errorf("expect tag %v, but got %v", []string{"a", "b"}, fields["tag"])
+	}
+
+	// The invalid form which cannot be redacted is not logged.
+	req = httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("password=%zz"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	attrs = serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+	if v, ok := attrs["reqbody"]; ok {
+		t.Errorf("unexpected reqbody '%v'", v)
+	}
+}
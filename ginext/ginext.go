@@ -0,0 +1,60 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ginext provides the gin middleware based on
+// "github.com/xgfone/go-apiserver-middleware-logger-ext".
+package ginext
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	loggerext "github.com/xgfone/go-apiserver-middleware-logger-ext"
+)
+
+// GinMiddleware returns a gin middleware, which wraps the request
+// and response, and emits an access log record by logger
+// like loggerext.Middleware.
+//
+// If logger is nil, use slog.Default() instead.
+func GinMiddleware(logger *slog.Logger) gin.HandlerFunc {
+	middleware := loggerext.Middleware(logger)
+	return func(c *gin.Context) {
+		writer := c.Writer
+		req := c.Request
+		if route := c.FullPath(); route != "" {
+			req = req.WithContext(loggerext.WithRoute(req.Context(), route))
+		}
+
+		middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c.Request = r
+			c.Writer = &responseWriter{ResponseWriter: writer, w: w}
+			c.Next()
+		})).ServeHTTP(writer, req)
+		c.Writer = writer
+	}
+}
+
+// responseWriter forwards the writes to the wrapped writer w.
+type responseWriter struct {
+	gin.ResponseWriter
+	w http.ResponseWriter
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter       { return w.w }
+func (w *responseWriter) WriteHeader(code int)              { w.w.WriteHeader(code) }
+func (w *responseWriter) Write(p []byte) (int, error)       { return w.w.Write(p) }
+func (w *responseWriter) WriteString(s string) (int, error) { return io.WriteString(w.w, s) }
@@ -0,0 +1,48 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginext
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	buf := bytes.NewBuffer(nil)
+	router := gin.New()
+	router.Use(GinMiddleware(slog.New(slog.NewTextHandler(buf, nil))))
+	router.GET("/path/:id", func(c *gin.Context) { c.String(http.StatusCreated, "ok") })
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/path/1", nil))
+
+	if rec.Code != http.StatusCreated || rec.Body.String() != "ok" {
+		t.Errorf("expect the response %d '%s', but got %d '%s'",
+			http.StatusCreated, "ok", rec.Code, rec.Body.String())
+	}
+
+	if log := buf.String(); !strings.Contains(log, "status=201") || !strings.Contains(log, "path=/path/1") ||
+		!strings.Contains(log, "route=/path/:id") {
+		t.Errorf("unexpected log record: %s", log)
+	}
+}
@@ -1,8 +1,10 @@
 module github.com/xgfone/go-apiserver-middleware-logger-ext
 
 require (
+	github.com/andybalholm/brotli v1.1.1
 	github.com/xgfone/gconf/v6 v6.5.0
 	github.com/xgfone/go-rawjson v0.1.0
+	golang.org/x/text v0.15.0
 )
 
 require (
@@ -0,0 +1,180 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/xgfone/go-rawjson"
+)
+
+// graphqlrequest is the GraphQL request over HTTP.
+type graphqlrequest struct {
+	Query         string          `json:"query"`
+	OperationName string          `json:"operationName"`
+	Variables     json.RawMessage `json:"variables"`
+}
+
+// isgraphql reports whether the path is a GraphQL endpoint
+// configured by the option graphqlpaths.
+func (c *Config) isgraphql(path string) bool {
+	for _, p := range c.GraphQLPaths {
+		if matchpath(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// getgraphqlbodyattr parses the GraphQL request from the JSON body,
+// and returns the attribute of the GraphQL operation.
+func getgraphqlbodyattr(c *Config, data []byte) (attr slog.Attr, ok bool) {
+	var req graphqlrequest
+	if err := json.Unmarshal(data, &req); err != nil || req.Query == "" {
+		return
+	}
+	return getgraphqlattr(c, req)
+}
+
+// getgraphqlqueryattr parses the GraphQL request from the url query
+// of the GET request, and returns the attribute of the GraphQL operation.
+func getgraphqlqueryattr(c *Config, r *http.Request) (attr slog.Attr, ok bool) {
+	query := r.URL.Query()
+	req := graphqlrequest{Query: query.Get("query"), OperationName: query.Get("operationName")}
+	if req.Query == "" {
+		return
+	}
+
+	if vars := query.Get("variables"); vars != "" {
+		req.Variables = json.RawMessage(vars)
+	}
+	return getgraphqlattr(c, req)
+}
+
+func getgraphqlattr(c *Config, req graphqlrequest) (attr slog.Attr, ok bool) {
+	attrs := make([]any, 0, 3)
+	if req.OperationName != "" {
+		attrs = append(attrs, slog.String("operation", req.OperationName))
+	}
+	if optype := getgraphqloptype(req.Query, req.OperationName); optype != "" {
+		attrs = append(attrs, slog.String("type", optype))
+	}
+
+	if vars := req.Variables; len(vars) > 0 && vars[0] == '{' && json.Valid(vars) {
+		if len(c.RedactFields) > 0 {
+			var err error
+			if vars, err = redactjsonfields(vars, c.RedactFields); err != nil {
+				vars = nil // Not log the variables which cannot be redacted.
+			}
+		}
+		if vars != nil {
+			attrs = append(attrs, slog.Any("variables", rawjson.Bytes(vars)))
+		}
+	}
+
+	return slog.Group("graphql", attrs...), true
+}
+
+type graphqlop struct{ optype, name string }
+
+// getgraphqloptype returns the type, that's, query, mutation or subscription,
+// of the operation named name in the GraphQL document, or the first if name
+// is empty.
+func getgraphqloptype(doc, name string) string {
+	var ops []graphqlop
+	var cur graphqlop
+	var expectname bool
+	var braces, parens int
+
+	for i := 0; i < len(doc); i++ {
+		switch ch := doc[i]; {
+		case ch == '#': // Comment
+			for i < len(doc) && doc[i] != '\n' {
+				i++
+			}
+
+		case ch == '"': // String or block string
+			if strings.HasPrefix(doc[i:], `"""`) {
+				end := strings.Index(doc[i+3:], `"""`)
+				if end < 0 {
+					return ""
+				}
+				i += end + 5
+			} else {
+				for i++; i < len(doc) && doc[i] != '"'; i++ {
+					if doc[i] == '\\' {
+						i++
+					}
+				}
+			}
+
+		case ch == '{':
+			if braces == 0 && parens == 0 {
+				if cur.optype == "" {
+					cur.optype = "query" // The query shorthand
+				}
+				ops, cur, expectname = append(ops, cur), graphqlop{}, false
+			}
+			braces++
+
+		case ch == '}':
+			braces--
+
+		case ch == '(':
+			parens++
+			expectname = false
+
+		case ch == ')':
+			parens--
+
+		case ch == '@':
+			expectname = false
+
+		case ch == '_' || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z'):
+			start := i
+			for i+1 < len(doc) && isgraphqlnamechar(doc[i+1]) {
+				i++
+			}
+
+			if braces > 0 || parens > 0 {
+				continue
+			}
+
+			switch ident := doc[start : i+1]; {
+			case expectname:
+				cur.name, expectname = ident, false
+			case cur.optype == "":
+				switch ident {
+				case "query", "mutation", "subscription", "fragment":
+					cur.optype, expectname = ident, true
+				}
+			}
+		}
+	}
+
+	for _, op := range ops {
+		if op.optype != "fragment" && (name == "" || op.name == name) {
+			return op.optype
+		}
+	}
+	return ""
+}
+
+func isgraphqlnamechar(ch byte) bool {
+	return ch == '_' || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9')
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGraphQLOpType(t *testing.T) {
+	for _, tc := range []struct {
+		doc    string
+		name   string
+		optype string
+	}{
+		{`{ user { id } }`, "", "query"},
+		{`query { user { id } }`, "", "query"},
+		{`mutation AddUser($name: String = "{") { addUser(name: $name) { id } }`, "", "mutation"},
+		{`# mutation X { x }
+		  subscription OnEvent @live { event { id } }`, "", "subscription"},
+		{`fragment F on User { id } query Q { ...F } mutation M { m }`, "", "query"},
+		{`query Q { q } mutation M { m }`, "M", "mutation"},
+		{`query Q { q }`, "X", ""},
+		{`query Q($s: String = """ } { """) { q }`, "Q", "query"},
+	} {
+		if optype := getgraphqloptype(tc.doc, tc.name); optype != tc.optype {
+			t.Errorf("%s: expect type '%s', but got '%s'", tc.doc, tc.optype, optype)
+		}
+	}
+}
+
+func TestGraphQLPaths(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logGraphQLPaths.Set([]string{"/graphql"})
+	_ = logRedactFields.Set([]string{"password"})
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logGraphQLPaths.Set([]string{})
+		_ = logRedactFields.Set([]string{})
+	}()
+
+	body := `{"query":"mutation Login($user: String!, $password: String!) { login(user: $user, password: $password) }",` +
+		`"operationName":"Login","variables":{"user":"u1","password":"secret"}}`
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+
+	if _, ok := attrs["reqbody"]; ok {
+		t.Errorf("unexpected reqbody: %v", attrs["reqbody"])
+	}
+
+	graphql := make(map[string]string)
+	for _, attr := range attrs["graphql"].Group() {
+		if v, ok := attr.Value.Any().(json.Marshaler); ok {
+			data, _ := v.MarshalJSON()
+			graphql[attr.Key] = string(data)
+		} else {
+			graphql[attr.Key] = attr.Value.String()
+		}
+	}
+	if v := graphql["operation"]; v != "Login" {
+		t.Errorf("expect operation '%s', but got '%s'", "Login", v)
+	}
+	if v := graphql["type"]; v != "mutation" {
+		t.Errorf("expect type '%s', but got '%s'", "mutation", v)
+	}
+	if v := graphql["variables"]; v != `{"user":"u1","password":"***"}` {
+		t.Errorf("unexpected variables '%s'", v)
+	}
+
+	query := url.Values{"query": []string{"{ me { id } }"}}
+	req = httptest.NewRequest(http.MethodGet, "/graphql?"+query.Encode(), nil)
+	attrs = serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+	if group := attrs["graphql"].Group(); len(group) != 1 || group[0].Value.String() != "query" {
+		t.Errorf("unexpected graphql: %v", group)
+	}
+
+	// The invalid GraphQL request is logged as the raw body.
+	req = httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"a":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	attrs = serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+	if _, ok := attrs["graphql"]; ok {
+		t.Errorf("unexpected graphql: %v", attrs["graphql"])
+	}
+	if v, ok := attrs["reqbody"].Any().(json.Marshaler); !ok {
+		t.Errorf("expect a raw json reqbody, but got '%v'", attrs["reqbody"])
+	} else if data, _ := v.MarshalJSON(); string(data) != `{"a":1}` {
+		t.Errorf("expect reqbody '%s', but got '%s'", `{"a":1}`, data)
+	}
+}
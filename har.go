@@ -0,0 +1,257 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HAR is the HTTP Archive 1.2 document of the captured traffic.
+//
+// See http://www.softwareishard.com/blog/har-12-spec/.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root log object of HAR.
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator is the creator of HAR.
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is an exported request and response pair of HAR.
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // Milliseconds
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+}
+
+// HARRequest is the request of the HAR entry.
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse is the response of the HAR entry.
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue is the name-value pair of the header, cookie or query.
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is the posted data of the request.
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent is the content of the response.
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+// HARTimings is the timings of the HAR entry in milliseconds.
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// HAREntry converts the flight record to the HAR entry.
+//
+// Since the bodies may be truncated by bodymaxlen, the body sizes
+// are the lengths of the captured bodies.
+func (r FlightRecord) HAREntry() HAREntry {
+	ms := float64(r.Duration) / float64(time.Millisecond)
+	entry := HAREntry{
+		StartedDateTime: r.Time,
+		Time:            ms,
+		Timings:         HARTimings{Wait: ms},
+		Request: HARRequest{
+			Method:      r.Method,
+			URL:         r.harurl(),
+			HTTPVersion: r.Proto,
+			Cookies:     []HARNameValue{},
+			Headers:     harheaders(r.ReqHeaders),
+			QueryString: harquery(r.URI),
+			HeadersSize: -1,
+			BodySize:    len(r.ReqBody),
+		},
+		Response: HARResponse{
+			Status:      r.Status,
+			StatusText:  http.StatusText(r.Status),
+			HTTPVersion: r.Proto,
+			Cookies:     []HARNameValue{},
+			Headers:     harheaders(r.RespHeaders),
+			RedirectURL: r.RespHeaders.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(r.RespBody),
+			Content: HARContent{
+				Size:     len(r.RespBody),
+				MimeType: r.RespHeaders.Get("Content-Type"),
+				Text:     r.RespBody,
+			},
+		},
+	}
+
+	if r.ReqBody != "" {
+		entry.Request.PostData = &HARPostData{
+			MimeType: r.ReqHeaders.Get("Content-Type"),
+			Text:     r.ReqBody,
+		}
+	}
+
+	return entry
+}
+
+func (r FlightRecord) harurl() string {
+	if r.Host == "" || strings.Contains(r.URI, "://") {
+		return r.URI
+	}
+
+	scheme := r.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return scheme + "://" + r.Host + r.URI
+}
+
+func harheaders(header http.Header) []HARNameValue {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([]HARNameValue, 0, len(header))
+	for _, name := range names {
+		for _, value := range header[name] {
+			values = append(values, HARNameValue{Name: name, Value: value})
+		}
+	}
+	return values
+}
+
+func harquery(uri string) []HARNameValue {
+	values := []HARNameValue{}
+	u, err := url.ParseRequestURI(uri)
+	if err != nil || u.RawQuery == "" {
+		return values
+	}
+
+	// Keep the order of the query arguments.
+	for _, kv := range strings.Split(u.RawQuery, "&") {
+		name, value, _ := strings.Cut(kv, "=")
+		if _name, err := url.QueryUnescape(name); err == nil {
+			name = _name
+		}
+		if _value, err := url.QueryUnescape(value); err == nil {
+			value = _value
+		}
+		values = append(values, HARNameValue{Name: name, Value: value})
+	}
+	return values
+}
+
+// ToHAR converts the flight records to the HAR document.
+func ToHAR(records []FlightRecord) HAR {
+	entries := make([]HAREntry, len(records))
+	for i, r := range records {
+		entries[i] = r.HAREntry()
+	}
+
+	return HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "go-apiserver-middleware-logger-ext", Version: "1.0"},
+		Entries: entries,
+	}}
+}
+
+// WriteHAR writes the requests and responses kept by the flight recorder
+// into w as the HAR document.
+func WriteHAR(w io.Writer) error {
+	return json.NewEncoder(w).Encode(ToHAR(flightrecords.snapshot()))
+}
+
+// WriteHARFile is the same as WriteHAR, but writes them into the file.
+func WriteHARFile(filename string) (err error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return
+	}
+
+	if err = WriteHAR(file); err != nil {
+		_ = file.Close()
+		return
+	}
+	return file.Close()
+}
+
+// HARHandler returns a http handler to export the requests and responses
+// kept by the flight recorder as the HAR document, which can be imported
+// into the browser devtools or the other HAR tools.
+//
+// Like DumpHandler, the headers and the JSON bodies are redacted.
+func HARHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="requests.har"`)
+		_ = WriteHAR(w)
+	})
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHARHandler(t *testing.T) {
+	_ = logQuery.Set(true)
+	_ = logFlightRecorder.Set(2)
+	defer func() {
+		_ = logQuery.Set(false)
+		_ = logFlightRecorder.Set(0)
+		flightrecords = flightRing{}
+	}()
+
+	req := httptest.NewRequest(http.MethodPost, "/path?a=1&b=x%20y", strings.NewReader("abc"))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Authorization", "Bearer token")
+	serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(201)
+		_, _ = w.Write([]byte("xyz"))
+	}, req)
+
+	rec := httptest.NewRecorder()
+	HARHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/har", nil))
+
+	var har HAR
+	if err := json.Unmarshal(rec.Body.Bytes(), &har); err != nil {
+		t.Fatal(err)
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 1 {
+		t.Fatalf("unexpected har: %+v", har)
+	}
+
+	entry := har.Log.Entries[0]
+	if entry.Request.Method != http.MethodPost || entry.Request.URL != "http://example.com/path?a=1&b=x%20y" {
+		t.Errorf("unexpected request: %+v", entry.Request)
+	}
+	if q := entry.Request.QueryString; len(q) != 2 || q[1] != (HARNameValue{Name: "b", Value: "x y"}) {
+		t.Errorf("unexpected query string: %+v", q)
+	}
+	if p := entry.Request.PostData; p == nil || p.Text != "abc" || p.MimeType != "text/plain" {
+		t.Errorf("unexpected post data: %+v", p)
+	}
+	for _, h := range entry.Request.Headers {
+		if h.Name == "Authorization" && h.Value != RedactedValue {
+			t.Errorf("expect Authorization '%s', but got '%s'", RedactedValue, h.Value)
+		}
+	}
+
+	resp := entry.Response
+	if resp.Status != 201 || resp.StatusText != "Created" || resp.Content.Text != "xyz" || resp.Content.MimeType != "text/plain" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	filename := filepath.Join(t.TempDir(), "requests.har")
+	if err := WriteHARFile(filename); err != nil {
+		t.Fatal(err)
+	} else if data, err := os.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	} else if string(data) != rec.Body.String() {
+		t.Errorf("expect '%s', but got '%s'", rec.Body.String(), data)
+	}
+
+	rec = httptest.NewRecorder()
+	HARHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/har", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expect status %d, but got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// jsonspace is the insignificant whitespace of JSON.
+const jsonspace = " \t\r\n"
+
+// filterjsonfields returns a new JSON object only containing the given fields
+// of the JSON object data, whose top-level keys are in the order of fields.
+//
+// Each field is the top-level key or the dot-separated path of the keys,
+// such as "error.code", and "*" in the path except the top level matches
+// any key. The arrays are transparent for the path like redactjsonfields,
+// and the nested fields are in the order of data.
+//
+// If data is not a JSON object, return nil.
+func filterjsonfields(data []byte, fields []string) []byte {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil || object == nil {
+		return nil
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)))
+	buf.WriteByte('{')
+	for i, field := range fields {
+		key, _, _ := strings.Cut(field, ".")
+		value, ok := object[key]
+		if !ok || slices.ContainsFunc(fields[:i], func(f string) bool { return strings.HasPrefix(f+".", key+".") }) {
+			continue // Not exist or has been written.
+		}
+
+		// Collect the nested paths of the key, or log the whole value.
+		var patterns [][]string
+		for _, f := range fields[i:] {
+			if f == key || f == key+".*" {
+				patterns = nil
+				break
+			} else if rest, ok := strings.CutPrefix(f, key+"."); ok {
+				patterns = append(patterns, strings.Split(rest, "."))
+			}
+		}
+
+		if len(patterns) > 0 {
+			if value = projectjson(value, patterns); value == nil {
+				continue
+			}
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		writejsonvalue(buf, key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes()
+}
+
+// projectjson returns a new JSON value only containing the values
+// at the given key paths, or nil if nothing matches.
+func projectjson(data []byte, patterns [][]string) []byte {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)))
+	if ok, err := projectjsonvalue(buf, dec, patterns, nil); err != nil || !ok {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func projectjsonvalue(buf *bytes.Buffer, dec *json.Decoder, patterns [][]string, path []string) (ok bool, err error) {
+	token, err := dec.Token()
+	if err != nil {
+		return
+	}
+
+	var child bytes.Buffer
+	switch token {
+	case json.Delim('{'):
+		buf.WriteByte('{')
+		for dec.More() {
+			if token, err = dec.Token(); err != nil {
+				return
+			}
+
+			keypath := append(path[:len(path):len(path)], token.(string))
+			child.Reset()
+
+			var matched bool
+			if matchjsonpath(patterns, keypath) {
+				var value json.RawMessage
+				if err = dec.Decode(&value); err != nil {
+					return
+				}
+				child.Write(value)
+				matched = true
+			} else if matched, err = projectjsonvalue(&child, dec, patterns, keypath); err != nil {
+				return
+			}
+
+			if matched {
+				if ok {
+					buf.WriteByte(',')
+				}
+				writejsonvalue(buf, token)
+				buf.WriteByte(':')
+				buf.Write(child.Bytes())
+				ok = true
+			}
+		}
+		buf.WriteByte('}')
+		_, err = dec.Token()
+
+	case json.Delim('['):
+		buf.WriteByte('[')
+		for dec.More() {
+			child.Reset()
+			var matched bool
+			if matched, err = projectjsonvalue(&child, dec, patterns, path); err != nil {
+				return
+			}
+
+			if matched {
+				if ok {
+					buf.WriteByte(',')
+				}
+				buf.Write(child.Bytes())
+				ok = true
+			}
+		}
+		buf.WriteByte(']')
+		_, err = dec.Token()
+	}
+
+	// The scalar value not matched by any path is dropped.
+	return
+}
+
+// redactjsonfields returns a new JSON document whose values at the given
+// field paths are replaced with RedactedValue, which is compacted.
+//
+// Each path is the dot-separated keys from the root, such as "card.number",
+// and "*" matches any key. The arrays are transparent for the path,
+// that's, "items.token" matches the key "token" of every element of "items".
+//
+// If no path may match, return data as-is without decoding it.
+func redactjsonfields(data []byte, paths []string) ([]byte, error) {
+	// The key may be escaped, such as "pass\u0077ord",
+	// so only skip the absent keys if there is no escape.
+	escaped := bytes.IndexByte(data, '\\') > -1
+
+	patterns := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		pattern := strings.Split(path, ".")
+		if last := pattern[len(pattern)-1]; escaped || last == "*" || bytes.Contains(data, []byte(`"`+last+`"`)) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	if len(patterns) == 0 {
+		return data, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)))
+	if err := redactjsonvalue(buf, dec, patterns, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func redactjsonvalue(buf *bytes.Buffer, dec *json.Decoder, patterns [][]string, path []string) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		buf.WriteByte('{')
+		for i := 0; dec.More(); i++ {
+			token, err := dec.Token()
+			if err != nil {
+				return err
+			}
+
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			key := token.(string)
+			writejsonvalue(buf, key)
+			buf.WriteByte(':')
+
+			keypath := append(path, key)
+			if matchjsonpath(patterns, keypath) {
+				var value json.RawMessage
+				if err := dec.Decode(&value); err != nil {
+					return err
+				}
+				writejsonvalue(buf, RedactedValue)
+			} else if err := redactjsonvalue(buf, dec, patterns, keypath); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		_, err = dec.Token()
+
+	case json.Delim('['):
+		buf.WriteByte('[')
+		for i := 0; dec.More(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := redactjsonvalue(buf, dec, patterns, path); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		_, err = dec.Token()
+
+	default:
+		writejsonvalue(buf, token)
+	}
+
+	return err
+}
+
+func writejsonvalue(buf *bytes.Buffer, value any) {
+	if number, ok := value.(json.Number); ok {
+		buf.WriteString(string(number))
+		return
+	}
+
+	data, _ := json.Marshal(value)
+	buf.Write(data)
+}
+
+func matchjsonpath(patterns [][]string, path []string) bool {
+	for _, pattern := range patterns {
+		if len(pattern) != len(path) {
+			continue
+		}
+
+		matched := true
+		for i, key := range pattern {
+			if key != "*" && key != path[i] {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// compactjsonbody strips the insignificant whitespaces of the whole JSON body
+// if the option jsoncompact is enabled, and returns it and its length.
+//
+// If the body is not buffered wholly or is invalid, return data and size as-is.
+func compactjsonbody(c *Config, ct string, data []byte, size int) ([]byte, int) {
+	if !c.JSONCompact || !isjsonct(ct) || len(data) == 0 || len(data) != size {
+		return data, size
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)))
+	if err := json.Compact(buf, data); err != nil {
+		return data, size
+	}
+	return buf.Bytes(), buf.Len()
+}
+
+// indentjson returns the JSON document indented by prettyIndent.
+func indentjson(data []byte) (string, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)*2))
+	if err := json.Indent(buf, data, "", prettyIndent); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
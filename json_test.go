@@ -0,0 +1,141 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBodyFields(t *testing.T) {
+	_ = logBodyFields.Set([]string{"id", "status"})
+	defer func() { _ = logBodyFields.Set([]string{}) }()
+
+	data := []byte(`{"password":"123","status":"ok","id":1,"name":"abc"}`)
+	attr, ok := getbodyattr(globalconfig(), data, "respbody", "application/json")
+	if !ok {
+		t.Fatal("expect to log the body, but got not")
+	}
+
+	body, _ := attr.Value.Any().(json.Marshaler).MarshalJSON()
+	if expect := `{"id":1,"status":"ok"}`; string(body) != expect {
+		t.Errorf("expect '%s', but got '%s'", expect, body)
+	}
+
+	if _, ok := getbodyattr(globalconfig(), []byte(`[1,2,3]`), "respbody", "application/json"); ok {
+		t.Error("expect to drop the non-object body, but got not")
+	}
+}
+
+func TestBodyFieldsPath(t *testing.T) {
+	fields := []string{"status", "error.*", "items.id", "meta.*.code", "error"}
+	data := []byte(`{"meta":{"a":{"code":1,"msg":"x"},"b":2},"items":[{"id":1,"name":"a"},{"id":2},{"name":"c"}],` +
+		`"error":{"code":"E1","message":"bad"},"token":"abc","status":"fail"}`)
+	expect := `{"status":"fail","error":{"code":"E1","message":"bad"},"items":[{"id":1},{"id":2}],"meta":{"a":{"code":1}}}`
+	if body := filterjsonfields(data, fields); string(body) != expect {
+		t.Errorf("expect '%s', but got '%s'", expect, body)
+	}
+
+	data = []byte(`{"items":"abc","meta":{"b":2}}`)
+	if expect := `{}`; string(filterjsonfields(data, fields)) != expect {
+		t.Errorf("expect '%s', but got '%s'", expect, filterjsonfields(data, fields))
+	}
+}
+
+func TestCTBodyFields(t *testing.T) {
+	_ = logBodyFields.Set([]string{"id"})
+	_ = logCTBodyFields.Set([]string{"application/problem+json=status; error.code"})
+	defer func() {
+		_ = logBodyFields.Set([]string{})
+		_ = logCTBodyFields.Set([]string{})
+	}()
+
+	data := []byte(`{"id":1,"status":400,"error":{"code":"E1","detail":"secret"}}`)
+	for ct, expect := range map[string]string{
+		"application/json":         `{"id":1}`,
+		"application/problem+json": `{"status":400,"error":{"code":"E1"}}`,
+	} {
+		c := globalconfig().forct(ct)
+		attr, ok := getbodyattr(c, data, "respbody", ct)
+		if !ok {
+			t.Errorf("%s: expect to log the body, but got not", ct)
+			continue
+		}
+
+		body, _ := attr.Value.Any().(json.Marshaler).MarshalJSON()
+		if string(body) != expect {
+			t.Errorf("%s: expect '%s', but got '%s'", ct, expect, body)
+		}
+	}
+
+	c := globalconfig()
+	c.CTBodyFields = []string{"application/json=", "application/json"}
+	if errs := c.Validate(); errs == nil {
+		t.Error("expect an error, but got nil")
+	}
+}
+
+func TestRedactFields(t *testing.T) {
+	_ = logRedactFields.Set([]string{"password", "card.number", "*.token"})
+	defer func() { _ = logRedactFields.Set([]string{}) }()
+
+	data := []byte(`{"password":"123","card":{"number":"4111","exp":"12/30"},` +
+		`"users":[{"token":"t1","id":1},{"token":"t2","id":2.5}],"ok":true,"nil":null}`)
+	attr, ok := getbodyattr(globalconfig(), data, "reqbody", "application/json")
+	if !ok {
+		t.Fatal("expect to log the body, but got not")
+	}
+
+	body, _ := attr.Value.Any().(json.Marshaler).MarshalJSON()
+	expect := `{"password":"***","card":{"number":"***","exp":"12/30"},` +
+		`"users":[{"token":"***","id":1},{"token":"***","id":2.5}],"ok":true,"nil":null}`
+	if string(body) != expect {
+		t.Errorf("expect '%s', but got '%s'", expect, body)
+	}
+
+	data = []byte(`{"name":"abc"}`)
+	if redacted, err := redactjsonfields(data, logRedactFields.Get()); err != nil {
+		t.Error(err)
+	} else if &redacted[0] != &data[0] {
+		t.Error("expect to return the original data")
+	}
+
+	if _, ok := getbodyattr(globalconfig(), []byte(`{"password":`), "reqbody", "application/json"); ok {
+		t.Error("expect to drop the invalid body, but got not")
+	}
+
+	// The escaped key must not skip the redaction.
+	data = []byte(`{"pass\u0077ord":"123"}`)
+	if redacted, err := redactjsonfields(data, logRedactFields.Get()); err != nil {
+		t.Error(err)
+	} else if string(redacted) != `{"password":"***"}` {
+		t.Errorf("expect '%s', but got '%s'", `{"password":"***"}`, redacted)
+	}
+
+	// The leading whitespaces must not skip the redaction.
+	attr, ok = getbodyattr(globalconfig(), []byte("\n {\"password\":\"123\"}"), "reqbody", "application/json")
+	if !ok {
+		t.Fatal("expect to log the body, but got not")
+	}
+	if body, _ := attr.Value.Any().(json.Marshaler).MarshalJSON(); string(body) != `{"password":"***"}` {
+		t.Errorf("expect '%s', but got '%s'", `{"password":"***"}`, body)
+	}
+
+	c := globalconfig()
+	c.ScrubPII = []string{"card"}
+	if data := scrubbody(c, "application/json", []byte("\t[4111111111111111]")); string(data) != `["***"]` {
+		t.Errorf("expect '%s', but got '%s'", `["***"]`, data)
+	}
+}
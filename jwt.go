@@ -0,0 +1,82 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// getjwtattr returns the attribute of the claims configured by the option
+// jwtclaims, which are parsed from the bearer token in the header
+// Authorization without verifying the signature.
+//
+// The raw token is never logged. If no claim is found, ok is false.
+func getjwtattr(c *Config, r *http.Request) (attr slog.Attr, ok bool) {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return
+	}
+
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return
+	}
+
+	var claims map[string]json.RawMessage
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return
+	}
+
+	attrs := make([]any, 0, len(c.JWTClaims))
+	for _, name := range c.JWTClaims {
+		if value, ok := claims[name]; ok {
+			attrs = append(attrs, slog.Any(name, decodeclaim(value)))
+		}
+	}
+
+	if len(attrs) == 0 {
+		return
+	}
+	return slog.Group("jwt", attrs...), true
+}
+
+func decodeclaim(data json.RawMessage) any {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil
+	}
+
+	if number, ok := value.(json.Number); ok {
+		if i, err := number.Int64(); err == nil {
+			return i
+		} else if f, err := number.Float64(); err == nil {
+			return f
+		}
+	}
+	return value
+}
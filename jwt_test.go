@@ -0,0 +1,62 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJWTClaims(t *testing.T) {
+	_ = logJWTClaims.Set([]string{"sub", "iss", "aud", "exp"})
+	defer func() { _ = logJWTClaims.Set([]string{}) }()
+
+	payload := base64.RawURLEncoding.EncodeToString(
+		[]byte(`{"sub":"user1","iss":"issuer","aud":["a","b"],"exp":1700000000,"email":"a@b.c"}`))
+
+	req := httptest.NewRequest(http.MethodGet, "/path", nil)
+	req.Header.Set("Authorization", "Bearer header."+payload+".signature")
+	attrs := collectAttrs(httptest.NewRecorder(), req)
+
+	claims := make(map[string]any)
+	for _, attr := range attrs["jwt"].Group() {
+		claims[attr.Key] = attr.Value.Any()
+	}
+
+	if len(claims) != 4 {
+		t.Errorf("expect %d claims, but got %v", 4, claims)
+	}
+	if v := claims["sub"]; v != "user1" {
+		t.Errorf("expect sub '%s', but got '%v'", "user1", v)
+	}
+	if v := claims["exp"]; v != int64(1700000000) {
+		t.Errorf("expect exp %d, but got %v", 1700000000, v)
+	}
+	if v, _ := claims["aud"].([]any); len(v) != 2 {
+		t.Errorf("unexpected aud %v", claims["aud"])
+	}
+	if _, ok := claims["email"]; ok {
+		t.Error("unexpected the claim email not configured")
+	}
+
+	for _, auth := range []string{"", "Basic dXNlcjpwYXNz", "Bearer invalid", "Bearer a.!!!.c"} {
+		req.Header.Set("Authorization", auth)
+		if _, ok := collectAttrs(httptest.NewRecorder(), req)["jwt"]; ok {
+			t.Errorf("unexpected jwt for Authorization '%s'", auth)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"log/slog"
+	"slices"
+	"sync/atomic"
+)
+
+// Keys is used to rename the keys of the attributes appended by Collect.
+//
+// The empty field means to use the default key, which is the field tag.
+type Keys struct {
+	Query       string `json:"query,omitempty"`
+	RequestLine string `json:"requestline,omitempty"`
+	ReqHeaders  string `json:"reqheaders,omitempty"`
+	RespHeaders string `json:"respheaders,omitempty"`
+	Cookies     string `json:"cookies,omitempty"`
+	ReqBody     string `json:"reqbody,omitempty"`
+	ReqBodyLen  string `json:"reqbodylen,omitempty"`
+	RespBody    string `json:"respbody,omitempty"`
+	RespBodyLen string `json:"respbodylen,omitempty"`
+	Route       string `json:"route,omitempty"`
+	Service     string `json:"service,omitempty"`
+	Referer     string `json:"referer,omitempty"`
+	Error       string `json:"herr,omitempty"`
+	TraceID     string `json:"trace_id,omitempty"`
+	SpanID      string `json:"span_id,omitempty"`
+}
+
+var attrkeys atomic.Pointer[Keys]
+
+// SetKeys sets the keys of the attributes appended by Collect,
+// such as Keys{ReqBody: "http.request.body"}.
+func SetKeys(keys Keys) {
+	attrkeys.Store(&keys)
+	invalidateconfig()
+}
+
+func loadkeys() (keys Keys) {
+	if k := attrkeys.Load(); k != nil {
+		keys = *k
+	}
+	return
+}
+
+// rename returns the configured key of the default key.
+func (k *Keys) rename(key string) string {
+	var name string
+	switch key {
+	case "query":
+		name = k.Query
+	case "requestline":
+		name = k.RequestLine
+	case "reqheaders":
+		name = k.ReqHeaders
+	case "respheaders":
+		name = k.RespHeaders
+	case "cookies":
+		name = k.Cookies
+	case "reqbody":
+		name = k.ReqBody
+	case "reqbodylen":
+		name = k.ReqBodyLen
+	case "respbody":
+		name = k.RespBody
+	case "respbodylen":
+		name = k.RespBodyLen
+	case "route":
+		name = k.Route
+	case "service":
+		name = k.Service
+	case "referer":
+		name = k.Referer
+	case "herr":
+		name = k.Error
+	case "trace_id":
+		name = k.TraceID
+	case "span_id":
+		name = k.SpanID
+	}
+
+	if name == "" {
+		return key
+	}
+	return name
+}
+
+// wrap returns a new appendAttr to rename the keys of the attributes
+// before appending them, or the original if no key is renamed.
+func (k *Keys) wrap(appendAttr func(...slog.Attr)) func(...slog.Attr) {
+	if *k == (Keys{}) {
+		return appendAttr
+	}
+
+	return func(attrs ...slog.Attr) {
+		attrs = slices.Clone(attrs) // Not modify the attributes of the caller.
+		for i := range attrs {
+			attrs[i].Key = k.rename(attrs[i].Key)
+		}
+		appendAttr(attrs...)
+	}
+}
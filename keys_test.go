@@ -0,0 +1,68 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetKeys(t *testing.T) {
+	_ = logQuery.Set(true)
+	_ = logReqBody.Set(true)
+	defer func() {
+		_ = logQuery.Set(false)
+		_ = logReqBody.Set(false)
+		SetKeys(Keys{})
+	}()
+
+	SetKeys(Keys{Query: "http.request.query", ReqBody: "http.request.body"})
+
+	req := httptest.NewRequest(http.MethodPost, "/path?a=1", strings.NewReader("abc"))
+	req.Header.Set("Content-Type", "text/plain")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+
+	if v := attrs["http.request.query"].String(); v != "a=1" {
+		t.Errorf("expect query '%s', but got '%s'", "a=1", v)
+	}
+	if v := attrs["http.request.body"].String(); v != "abc" {
+		t.Errorf("expect reqbody '%s', but got '%s'", "abc", v)
+	}
+	if v := attrs["reqbodylen"].Int64(); v != 3 {
+		t.Errorf("expect reqbodylen %d, but got %d", 3, v)
+	}
+	if _, ok := attrs["query"]; ok {
+		t.Error("unexpected the default key query")
+	}
+}
+
+func TestKeysWrap(t *testing.T) {
+	var appended []slog.Attr
+	keys := Keys{Query: "http.request.query"}
+	appendAttr := keys.wrap(func(attrs ...slog.Attr) { appended = append(appended, attrs...) })
+
+	attrs := []slog.Attr{slog.String("query", "a=1")}
+	appendAttr(attrs...)
+
+	if attrs[0].Key != "query" {
+		t.Errorf("expect the key of the caller '%s', but got '%s'", "query", attrs[0].Key)
+	}
+	if len(appended) != 1 || appended[0].Key != "http.request.query" {
+		t.Errorf("unexpected appended attributes: %v", appended)
+	}
+}
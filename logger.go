@@ -27,13 +27,28 @@
 package loggerext
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
 	"io"
 	"log/slog"
+	"math/rand"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 	"unsafe"
 
 	"github.com/xgfone/gconf/v6"
@@ -43,26 +58,256 @@ import (
 var (
 	group          = gconf.Group("log")
 	logQuery       = group.NewBool("query", false, "If true, log the request query.")
+	logRequestLine = group.NewBool("requestline", false, "If true, log the request line, such as 'GET /path?query HTTP/1.1'.")
 	logReqBody     = group.NewBool("reqbody", false, "If true, log the request body.")
 	logRespBody    = group.NewBool("respbody", false, "If true, log the response body.")
 	logReqHeaders  = group.NewBool("reqheaders", false, "If true, log the request headers.")
 	logRespHeaders = group.NewBool("respheaders", false, "If true, log the response headers.")
 
+	logRedactHeaders = group.NewStringSlice("redactheaders",
+		[]string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"},
+		"The names of the request and response headers whose values are redacted.")
+	logAuthMask = group.NewString("authmask", "redact",
+		"The strategy to mask the headers Authorization and Proxy-Authorization regardless of the option redactheaders, "+
+			"such as redact, drop, scheme, prefix or hash. redact means to use the option redactheaders.").
+		Validators(gconf.NewStrArrayValidator([]string{"redact", "drop", "scheme", "prefix", "hash"}))
+	logAuthPrefixLen = group.NewInt("authprefixlen", 4,
+		"The length of the prefix of the credentials kept by the authmask strategy prefix.")
+	logCookies       = group.NewBool("cookies", false, "If true, log the request cookies.")
+	logRedactCookies = group.NewStringSlice("redactcookies",
+		[]string{"session", "sessionid", "session_id", "sid", "JSESSIONID", "PHPSESSID", "token", "auth", "auth_token"},
+		"The names of the request cookies whose values are redacted.")
+	logRedactFields = group.NewStringSlice("redactfields", nil,
+		"The dot-separated paths of the fields in the JSON body whose values are redacted, and '*' matches any key.")
+	logRedactXML = group.NewStringSlice("redactxml", nil,
+		"The dot-separated paths of the elements in the XML body from the root whose contents are redacted, and '*' matches any element.")
+	logRedactQueries = group.NewStringSlice("redactqueries",
+		[]string{"token", "access_token", "api_key", "apikey", "password", "secret"},
+		"The keys of the request query whose values are redacted in the logged query and request line.")
+	logScrubPII = group.NewStringSlice("scrubpii", nil,
+		"The names of the PII patterns in order, such as email, card, iban and phone, "+
+			"whose matched values in the logged bodies and headers are redacted. Others may be registered by SetPIIPattern.")
+
+	logClientIP = group.NewBool("clientip", false,
+		"If true, log the real client ip as client_ip, which honors the proxy headers from the trusted proxies.")
+	logClientIPHeaders = group.NewStringSlice("clientipheaders", []string{"X-Forwarded-For", "X-Real-IP", "Forwarded"},
+		"The headers in order to get the real client ip if the direct peer is a trusted proxy.")
+	logTrustedProxies = group.NewStringSlice("trustedproxies", nil,
+		"The CIDRs or IPs of the trusted proxies, such as 10.0.0.0/8, whose proxy headers are honored.")
+
+	logGraphQLPaths = group.NewStringSlice("graphqlpaths", nil,
+		"The paths of the GraphQL endpoints, whose request bodies are logged as the group graphql "+
+			"containing the operation, type and redacted variables instead. The path ending with / is a prefix.")
+
+	logBasicUser = group.NewBool("basicuser", false,
+		"If true, log the username of the HTTP Basic authentication as user, but never the password.")
+	logJWTClaims = group.NewStringSlice("jwtclaims", nil,
+		"The claims, such as sub, iss, aud and exp, parsed from the bearer token without verifying to log as the group jwt.")
+
+	logTLS = group.NewBool("tls", false,
+		"If true, log the negotiated TLS version, cipher suite, server name (SNI) and application protocol (ALPN).")
+
+	logClientCert       = group.NewBool("clientcert", false, "If true, log the subject common name of the TLS client certificate.")
+	logClientCertDetail = group.NewBool("clientcertdetail", false,
+		"If true, also log the subject, serial number and SHA-256 fingerprint of the TLS client certificate.")
+
+	logReferer         = group.NewBool("referer", false, "If true, log the request header Referer.")
+	logRefererPathOnly = group.NewBool("refererpathonly", false,
+		"If true, only log the path of the request header Referer without the host and query.")
+
+	logTrailers = group.NewBool("trailers", false,
+		"If true, log the request and response trailers as reqtrailers and resptrailers, which are redacted like the headers.")
+
+	logStatus = group.NewBool("status", false,
+		"If true, log the response status code and the number of the written bytes as status and respwritten, "+
+			"which may be disabled if the outer logger has logged them.")
+
+	logCurl = group.NewBool("curl", false,
+		"If true, log the request as a curl command to reproduce it, whose headers and body are redacted.")
+
+	logMutatingBodyOnly = group.NewBool("mutatingbodyonly", false,
+		"If true, only log the request and response bodies for the methods POST, PUT, PATCH and DELETE.")
+
 	logBodyMaxLen = group.NewInt("bodymaxlen", 2048,
 		"The maximum length of the request or response body to log.")
+	logBodyMaxLens = group.NewStringSlice("bodymaxlens", nil,
+		"The maximum lengths of the body per content type overriding bodymaxlen, each of which is in the format \"ContentType=MaxLen\", "+
+			"such as \"application/json=8192\" and \"text/*=1024\". MaxLen 0 means not to log the body content of the content type.")
+	logBodyBudget = group.NewInt("bodybudget", 0,
+		"The maximum total bytes of the response bodies buffered concurrently. 0 means no limit.")
+	logBufSize = group.NewInt("bufsize", 512,
+		"The initial capacity of the new buffer to buffer the body.")
+	logBufMaxCap = group.NewInt("bufmaxcap", 1<<20,
+		"The maximum capacity of the buffer put back into the pool, and the larger is discarded. 0 means no limit.")
+	logZeroCopy = group.NewBool("zerocopy", false,
+		"If true, reference the buffered body in the attributes without copying, "+
+			"which is unsafe if the log record is handled after Release, such as by the asynchronous slog handler.")
+	logLazyReqBody = group.NewBool("lazyreqbody", false,
+		"If true, capture the request body as the handler reads it instead of reading it in advance, "+
+			"so the body not read by the handler is not logged.")
+	logSlowThreshold = group.NewDuration("slowthreshold", 0,
+		"If greater than 0, only log the headers and bodies of the request taking longer than it.")
+	logBodySampleRate = group.NewFloat64("bodysamplerate", 1,
+		"The sampling rate in [0, 1] of the requests whose bodies are logged, which is decided once per request.")
+	logBinaryTypes = group.NewStringSlice("binarytypes", nil,
+		"The binary content types of the body, such as application/octet-stream, to log the encoded prefix.")
+	logBinarySnippetLen = group.NewInt("binarysnippetlen", 64,
+		"The maximum length of the prefix of the binary body to log.")
+	logBinaryEncoding = group.NewString("binaryencoding", "base64",
+		"The encoding of the prefix of the binary body, such as base64 or hex.").
+		Validators(gconf.NewStrArrayValidator([]string{"base64", "hex"}))
+	logBodyHash = group.NewBool("bodyhash", false,
+		"If true, log the SHA-256 hash of the whole request and response bodies.")
+	logBodyHashOnly = group.NewBool("bodyhashonly", false,
+		"If true, only log the hash instead of the content of the bodies when the option bodyhash is enabled.")
+	logMultipartMeta = group.NewBool("multipartmeta", false,
+		"If true, only log the metadata of the parts of the multipart/form-data request body, such as the names and sizes.")
+	logBodyOnError = group.NewBool("bodyonerror", false,
+		"If true, only log the request and response bodies when the response status code is 4xx or 5xx.")
+	logTruncateBody = group.NewBool("truncatebody", false,
+		"If true, log the first bodymaxlen bytes of the oversized body instead of skipping it.")
+	logTruncateTail = group.NewInt("truncatetail", 0,
+		"If greater than 0, the truncated body consists of the first bodymaxlen-truncatetail bytes and the last truncatetail bytes joined by the marker \"…[truncated N bytes]…\".")
+	logXML = group.NewBool("xml", false,
+		"If true, log the XML bodies, such as application/xml, text/xml and the content types with the suffix +xml.")
+	logXMLCompact = group.NewBool("xmlcompact", false,
+		"If true, strip the comments and the insignificant whitespaces between the elements of the XML body.")
+	logJSONCompact = group.NewBool("jsoncompact", false,
+		"If true, strip the insignificant whitespaces of the whole JSON body before checking bodymaxlen and logging it.")
+	logPrettyBody = group.NewBool("prettybody", false,
+		"If true, indent the JSON and XML bodies as the multi-line strings for development, which are readable with the text-based handlers printing them as-is.")
+	logFormBody = group.NewBool("formbody", false,
+		"If true, log the application/x-www-form-urlencoded body as a group of the fields, whose values of the keys in redactqueries are redacted.")
+	logNDJSONLines = group.NewInt("ndjsonlines", 0,
+		"If greater than 0, only capture the first ndjsonlines lines of the streaming JSON body, such as application/x-ndjson, and log them as an array. 0 means disabled.")
+	logStreamMaxLen = group.NewInt("streammaxlen", 0,
+		"If greater than 0, only buffer the first streammaxlen bytes of the response body without Content-Length, such as the chunked stream, and log them as truncated. 0 means disabled.")
+
 	logBodyTypes = group.NewStringSlice("bodytypes", []string{
 		"text/*", "application/json", "application/x-www-form-urlencoded",
 	}, "The content types of the request or response body to log.")
+
+	logIncludeTextTypes = group.NewBool("includetexttypes", false,
+		"If true, also log the body of the common text or structured content types, such as text/plain and application/xml.")
+
+	logBodyFields = group.NewStringSlice("bodyfields", nil,
+		"If not empty, only log the given fields of the JSON object body, and drop others. "+
+			"Each field is the top-level key or the dot-separated path such as \"error.code\", and \"*\" matches any nested key.")
+	logCTBodyFields = group.NewStringSlice("ctbodyfields", nil,
+		"The fields of the JSON body per content type overriding bodyfields, each of which is in the format \"ContentType=Field1;Field2\", "+
+			"such as \"application/problem+json=type;status;error.*\".")
+
+	logBinaryThreshold = group.NewFloat64("binarythreshold", 0.1,
+		"If the ratio of the invalid UTF-8 bytes in the text body exceeds it, log the body as base64. 0 means disabled.")
+
+	logEmptyBody = group.NewString("emptybody", "omit",
+		"The representation of the empty request or response body, such as omit, empty-string or null.").
+		Validators(gconf.NewStrArrayValidator([]string{"omit", "empty-string", "null"}))
+
+	logErrorBodies = group.NewBool("logerrorbodies", false,
+		"If true, log the small response body regardless of the content type when the status code is 4xx or 5xx.")
+	logErrorBodyMaxLen = group.NewInt("errorbodymaxlen", 1024,
+		"The maximum length of the error response body to log regardless of the content type.")
+
+	logDefaultService = group.NewString("defaultservice", "default",
+		"The service name of the path which is not mapped by SetServiceMapping.")
+
+	logStatuses = group.NewStringSlice("statuses", nil,
+		"If not empty, only log the requests whose response status codes match any of them, such as 4xx, 5xx or 404.")
+
+	logDebugHeader = group.NewString("debugheader", "",
+		"The request header to force the full header and body logging for the single request if its value matches debugtoken.")
+	logDebugToken = group.NewString("debugtoken", "",
+		"The secret token matched with the value of the request header debugheader.")
+
+	logSkipHeaders = group.NewStringSlice("skipheaders", nil,
+		"The request headers to skip logging, each of which is in the format \"Name: value\", or \"Name\" to match any non-empty value.")
+	logForceHeaders = group.NewStringSlice("forceheaders", nil,
+		"The request headers to force logging regardless of the ignored paths, the skip headers and the body sample rate, "+
+			"each of which is in the format \"Name: value\", or \"Name\" to match any non-empty value.")
+
+	logPushHeader = group.NewString("pushheader", "",
+		"If not empty, the request with the header is considered as a HTTP/2 pushed request.")
+
+	logSizeWindow = group.NewInt("sizewindow", 0,
+		"The number of the latest body sizes to calculate the percentiles by BodySizeStats, such as 1024. 0 means disabled.")
+
+	logEventBuffer = group.NewInt("eventbuffer", 100,
+		"The buffer size of the channel returned by Subscribe.")
+
+	logAttrOrder = group.NewStringSlice("attrorder", nil,
+		"The emission order of the collected attributes by their keys renamed by SetKeys, and the others are emitted after them.")
+
+	logGroupAttrs = group.NewBool("groupattrs", false,
+		"If true, emit the request and response attributes under the groups request and response, such as request.body.")
+
+	logFlightRecorder = group.NewInt("flightrecorder", 0,
+		"The number of the latest requests and responses kept in memory for DumpHandler, "+
+			"whose bodies are captured even if not logged. 0 means disabled.")
+
+	logSpillDir = group.NewString("spilldir", "",
+		"The directory to spill the request and response bodies larger than bodymaxlen into the temp files. Empty means disabled. "+
+			"The spill files are not redacted, so the bodies redacted by redactfields, bodyfields, ctbodyfields, redactxml, formbody or scrubpii are not spilled.")
+	logSpillMaxLen = group.NewInt("spillmaxlen", 64<<20,
+		"The maximum length of the spilled body. 0 means no limit.")
+	logSpillRetention = group.NewDuration("spillretention", time.Hour,
+		"The retention of the spill files, which are removed after that. 0 means never removed.")
+
+	logCaptureDir = group.NewString("capturedir", "",
+		"The directory to write each captured request into a JSON file to replay it. Empty means disabled.")
+	logCaptureSampleRate = group.NewFloat64("capturesamplerate", 1,
+		"The sample rate in [0, 1] of the requests written into capturedir.")
+	logCaptureMaxFiles = group.NewInt("capturemaxfiles", 1000,
+		"The maximum number of the files written into capturedir by the process. 0 means no limit.")
+
+	logStrictOrdering = group.NewBool("strictordering", false,
+		"If true, append the attribute loggerextmisconfigured=true when the request is not wrapped before collecting.")
 )
 
-var bufpool = sync.Pool{New: func() interface{} { return bytes.NewBuffer(make([]byte, 0, 512)) }}
+// bodybudgetused is the total bytes of the response bodies buffered currently.
+var bodybudgetused atomic.Int64
 
-func getbuffer() *bytes.Buffer  { return bufpool.Get().(*bytes.Buffer) }
-func putbuffer(b *bytes.Buffer) { b.Reset(); bufpool.Put(b) }
+// reservebudget reserves n bytes from the global body budget limit.
+func reservebudget(n, limit int) bool {
+	if bodybudgetused.Add(int64(n)) > int64(limit) {
+		bodybudgetused.Add(-int64(n))
+		return false
+	}
+	return true
+}
+
+var bufpool = sync.Pool{New: func() interface{} {
+	metrics.poolmisses.Add(1)
+	return bytes.NewBuffer(make([]byte, 0, max(logBufSize.Get(), 0)))
+}}
+
+func getbuffer() *bytes.Buffer {
+	metrics.poolgets.Add(1)
+	return bufpool.Get().(*bytes.Buffer)
+}
+
+func putbuffer(b *bytes.Buffer) {
+	metrics.buffered.Add(int64(b.Len()))
+	if maxcap := logBufMaxCap.Get(); maxcap > 0 && b.Cap() > maxcap {
+		return // Not keep the buffer grown by a large body in the pool.
+	}
+
+	b.Reset()
+	bufpool.Put(b)
+}
 
 type ctxkeytype int8
 
-var logrespkey = ctxkeytype(0)
+var (
+	logrespkey     = ctxkeytype(0)
+	logdisabledkey = ctxkeytype(1)
+	pushedkey      = ctxkeytype(2)
+	startkey       = ctxkeytype(3)
+	samplekey      = ctxkeytype(4)
+	logreqkey      = ctxkeytype(5)
+	routekey       = ctxkeytype(6)
+	capturekey     = ctxkeytype(7)
+)
 
 func logRespFromContext(ctx context.Context) (log, ok bool) {
 	if v := ctx.Value(logrespkey); v != nil {
@@ -71,6 +316,13 @@ func logRespFromContext(ctx context.Context) (log, ok bool) {
 	return
 }
 
+func logReqFromContext(ctx context.Context) (log, ok bool) {
+	if v := ctx.Value(logreqkey); v != nil {
+		return v.(bool), true
+	}
+	return
+}
+
 // DisableLogRespBody returns a new context to set a flag to indicate
 // not to log the response body.
 //
@@ -79,9 +331,188 @@ func DisableLogRespBody(ctx context.Context) context.Context {
 	return context.WithValue(ctx, logrespkey, false)
 }
 
+// EnableLogRespBody returns a new context to set a flag to indicate
+// to log the response body, even if the option respbody is disabled.
+//
+// It must be set before WrapHandler, such as by the previous middleware.
+func EnableLogRespBody(ctx context.Context) context.Context {
+	return context.WithValue(ctx, logrespkey, true)
+}
+
+// DisableLogReqBody returns a new context to set a flag to indicate
+// not to log the request body.
+//
+// It must be set before WrapHandler, such as by the previous middleware.
+func DisableLogReqBody(ctx context.Context) context.Context {
+	return context.WithValue(ctx, logreqkey, false)
+}
+
+// EnableLogReqBody returns a new context to set a flag to indicate
+// to log the request body, even if the option reqbody is disabled.
+//
+// It must be set before WrapHandler, such as by the previous middleware.
+func EnableLogReqBody(ctx context.Context) context.Context {
+	return context.WithValue(ctx, logreqkey, true)
+}
+
+// DisableLogging returns a new context to set a flag to indicate
+// not to log the request at all, which is checked by Enabled,
+// and WrapHandler does not buffer any body of the request.
+//
+// It must be set before WrapHandler, such as by the previous middleware.
+func DisableLogging(ctx context.Context) context.Context {
+	return context.WithValue(ctx, logdisabledkey, true)
+}
+
+func loggingDisabled(ctx context.Context) bool {
+	return ctx.Value(logdisabledkey) != nil
+}
+
+// SampleBody returns a new context to set the sampling decision of the bodies
+// of the request, which overrides the option bodysamplerate.
+//
+// It must be set before WrapHandler, such as by the previous middleware.
+func SampleBody(ctx context.Context, sampled bool) context.Context {
+	return context.WithValue(ctx, samplekey, sampled)
+}
+
+// issampled decides whether to log the bodies of the request
+// by the option bodysamplerate only once when wrapping it.
+func issampled(c *Config, r *http.Request) bool {
+	if sampled, ok := r.Context().Value(samplekey).(bool); ok {
+		return sampled
+	}
+
+	switch {
+	case c.isforced(r.Header):
+		return true
+	case c.BodySampleRate >= 1:
+		return true
+	case c.BodySampleRate <= 0:
+		return false
+	default:
+		return rand.Float64() < c.BodySampleRate
+	}
+}
+
+// MarkPushed returns a new context to set a flag to indicate that
+// the request is originated from the HTTP/2 server push,
+// so that Collect appends the attribute pushed=true.
+//
+// The standard library does not expose whether a request is pushed,
+// so it must be marked by the application, or add the header
+// configured by the option pushheader into http.PushOptions.Header
+// when pushing the resource.
+func MarkPushed(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pushedkey, true)
+}
+
+func ispushed(c *Config, r *http.Request) bool {
+	if r.Context().Value(pushedkey) != nil {
+		return true
+	}
+	if header := c.PushHeader; header != "" {
+		return r.Header.Get(header) != ""
+	}
+	return false
+}
+
+// WithRoute returns a new context to carry the matched route template
+// of the request, such as "/users/{id}", so that Collect appends it
+// as the attribute route.
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routekey, route)
+}
+
+var routeGetter func(*http.Request) string
+
+// SetRouteGetter sets the getter to get the matched route template
+// of the request, which is used when the route is not set by WithRoute.
+//
+// For chi, the route pattern can be got after routing, for example,
+//
+//	SetRouteGetter(func(r *http.Request) string {
+//		if c := chi.RouteContext(r.Context()); c != nil {
+//			return c.RoutePattern()
+//		}
+//		return ""
+//	})
+func SetRouteGetter(getter func(*http.Request) string) {
+	routeGetter = getter
+}
+
+func getroute(r *http.Request) string {
+	if route, _ := r.Context().Value(routekey).(string); route != "" {
+		return route
+	}
+	if routeGetter != nil {
+		return routeGetter(r)
+	}
+	return ""
+}
+
+var traceGetter func(context.Context) (traceID, spanID string)
+
+// SetTraceGetter sets the getter to get the trace id and span id
+// from the request context, so that Collect appends them as the attributes
+// trace_id and span_id to join the logs with the distributed traces.
+//
+// For OpenTelemetry, see the sub-module otelext.
+func SetTraceGetter(getter func(context.Context) (traceID, spanID string)) {
+	traceGetter = getter
+}
+
+func appendtraceattrs(r *http.Request, appendAttr func(...slog.Attr)) {
+	if traceGetter == nil {
+		return
+	}
+
+	traceID, spanID := traceGetter(r.Context())
+	if traceID != "" {
+		appendAttr(slog.String("trace_id", traceID))
+	}
+	if spanID != "" {
+		appendAttr(slog.String("span_id", spanID))
+	}
+}
+
+var identityExtractor func(*http.Request) []slog.Attr
+
+// SetIdentityExtractor sets the extractor to extract the identity attributes
+// of the request, such as user_id, tenant and api_client, which are set
+// by the auth middleware, so that Collect appends them for audit.
+func SetIdentityExtractor(extractor func(*http.Request) []slog.Attr) {
+	identityExtractor = extractor
+}
+
+var errorGetter func(*http.Request) error
+
+// SetErrorGetter sets the getter to get the business error of the handler
+// from the request, which is appended as the attribute herr by Collect.
+//
+// The request passed to getter is the one passed to Collect, which cannot
+// see the context values added by the inner handler. So the handler error
+// should be stored into a mutable holder, which has been put into the request
+// context by the previous middleware. For go-apiserver, the handler error
+// is stored in the field Err of the request context got by reqresp.GetContext,
+// for example,
+//
+//	SetErrorGetter(func(r *http.Request) error {
+//		if c := reqresp.GetContext(r.Context()); c != nil {
+//			return c.Err
+//		}
+//		return nil
+//	})
+func SetErrorGetter(getter func(*http.Request) error) {
+	errorGetter = getter
+}
+
 // WrapHandler wraps a http handler and returns a new,
 // which will replace the request and response writer,
 // so must be used before the logger middleware.
+//
+// If it is used after the logger middleware, Collect will warn it once.
+// Or, use InstallBoth to install them in the right order.
 func WrapHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w, r = WrapReqRespBody(w, r)
@@ -90,16 +521,11 @@ func WrapHandler(next http.Handler) http.Handler {
 	})
 }
 
-var ignorepaths []func(path string) bool
-
-func isignore(path string) bool {
-	for _, ignore := range ignorepaths {
-		if ignore(path) {
-			return true
-		}
-	}
-	return false
-}
+var (
+	ignorelock     sync.Mutex
+	ignorepathstrs atomic.Pointer[[]string]
+	ignorepatterns atomic.Pointer[[]func(urlpath string) bool]
+)
 
 // AppendIgnorePath appends the ignored path, which is not logged.
 //
@@ -111,67 +537,828 @@ func AppendIgnorePath(path string) {
 		return
 	}
 
-	if strings.HasSuffix(path, "/") {
-		ignorepaths = append(ignorepaths, func(urlpath string) (ignore bool) {
-			return strings.HasPrefix(urlpath, path)
-		})
-	} else {
-		ignorepaths = append(ignorepaths, func(urlpath string) (ignore bool) {
-			return urlpath == path
+	ignorelock.Lock()
+	defer ignorelock.Unlock()
+	paths := append(slices.Clone(loadignorepaths()), path)
+	ignorepathstrs.Store(&paths)
+	invalidateconfig()
+}
+
+// setignorepaths replaces all the ignored paths.
+func setignorepaths(paths []string) {
+	paths = slices.Clone(paths)
+	ignorelock.Lock()
+	ignorepathstrs.Store(&paths)
+	ignorelock.Unlock()
+	invalidateconfig()
+}
+
+func loadignorepaths() []string {
+	if paths := ignorepathstrs.Load(); paths != nil {
+		return *paths
+	}
+	return nil
+}
+
+// AppendIgnorePattern appends the ignored path pattern, which is not logged.
+//
+// pattern may be a glob string, such as "/api/*/health", which is matched
+// by path.Match, or a compiled *regexp.Regexp. Or, panic.
+func AppendIgnorePattern(pattern any) {
+	switch p := pattern.(type) {
+	case string:
+		if _, err := path.Match(p, ""); err != nil {
+			panic(fmt.Errorf("invalid ignore pattern '%s': %w", p, err))
+		}
+		appendignorepattern(func(urlpath string) bool {
+			ok, _ := path.Match(p, urlpath)
+			return ok
 		})
+
+	case *regexp.Regexp:
+		appendignorepattern(p.MatchString)
+
+	default:
+		panic(fmt.Errorf("unsupported ignore pattern type %T", pattern))
+	}
+}
+
+func appendignorepattern(match func(urlpath string) bool) {
+	ignorelock.Lock()
+	defer ignorelock.Unlock()
+	patterns := append(slices.Clone(loadignorepatterns()), match)
+	ignorepatterns.Store(&patterns)
+}
+
+func loadignorepatterns() []func(urlpath string) bool {
+	if patterns := ignorepatterns.Load(); patterns != nil {
+		return *patterns
+	}
+	return nil
+}
+
+// newpathmatcher returns a path matcher, which is a prefix matching
+// if path ends with "/", or an equal matching.
+func newpathmatcher(path string) func(urlpath string) bool {
+	return func(urlpath string) bool { return matchpath(path, urlpath) }
+}
+
+// matchpath reports whether urlpath matches path, which is a prefix matching
+// if path ends with "/", or an equal matching.
+func matchpath(path, urlpath string) bool {
+	if strings.HasSuffix(path, "/") {
+		return strings.HasPrefix(urlpath, path)
+	}
+	return urlpath == path
+}
+
+type pathct struct {
+	match func(string) bool
+	ct    string
+}
+
+var reqpathcts, resppathcts []pathct
+
+// ForceBodyContentType forces the content type of the request body
+// of the path to ct for logging, regardless of the header Content-Type.
+//
+// If path ends with "/", it is a prefix matching; Or, an equal matching.
+func ForceBodyContentType(path, ct string) {
+	reqpathcts = append(reqpathcts, pathct{match: newpathmatcher(path), ct: ct})
+}
+
+// ForceRespBodyContentType is the same as ForceBodyContentType,
+// but forces the content type of the response body, for example,
+// log the body of application/octet-stream as application/x-ndjson.
+func ForceRespBodyContentType(path, ct string) {
+	resppathcts = append(resppathcts, pathct{match: newpathmatcher(path), ct: ct})
+}
+
+// getpathct returns the forced content type of the path,
+// or the content type in header.
+func getpathct(pathcts []pathct, path string, header http.Header) string {
+	for _, pct := range pathcts {
+		if pct.match(path) {
+			return pct.ct
+		}
+	}
+	return getContentType(header)
+}
+
+var enabledFunc func(*http.Request) bool
+
+// SetEnabledFunc sets the predicate to decide whether to log the request,
+// which is called by Enabled only when the request is not ignored
+// by the built-in rules, such as the ignored paths and the skip headers.
+//
+// If enabled is nil, clear it.
+func SetEnabledFunc(enabled func(*http.Request) bool) {
+	enabledFunc = enabled
+}
+
+// Enabled reports whether to log the request.
+func Enabled(req *http.Request) bool {
+	if req.URL.Path == "/" || loggingDisabled(req.Context()) {
+		return false
+	}
+
+	c := getconfig(req)
+	if c.isforced(req.Header) {
+		return true
+	}
+	if c.isignore(req.URL.Path) || c.isskipped(req.Header) {
+		return false
+	}
+	return enabledFunc == nil || enabledFunc(req)
+}
+
+// MiddlewareInserter is used to insert the http middlewares at the front
+// of the middleware chain, such as the middleware manager of go-apiserver.
+type MiddlewareInserter interface {
+	InsertFunc(middlewares ...func(http.Handler) http.Handler)
+}
+
+// InstallBoth inserts WrapHandler and the logger middleware into middlewares
+// in the right order, that's, WrapHandler is installed before logger.
+func InstallBoth(middlewares MiddlewareInserter, logger func(http.Handler) http.Handler) {
+	middlewares.InsertFunc(WrapHandler, logger)
+}
+
+var misorderwarned atomic.Bool
+
+// checkwrapped reports whether the request has been wrapped by WrapReqRespBody
+// when it is required, and warns only once if not.
+func checkwrapped(c *Config, r *http.Request) (ok bool) {
+	if !c.wrapenabled() || loggingDisabled(r.Context()) || r.Method == http.MethodConnect ||
+		isupgrade(r.Header) || (c.isskipped(r.Header) && !c.isforced(r.Header)) {
+		return true
+	}
+
+	if r.Context().Value(wrappedkey) != nil {
+		return true
+	}
+
+	if misorderwarned.CompareAndSwap(false, true) {
+		slog.Warn("loggerext: the request is not wrapped before collecting, "+
+			"and WrapHandler may be installed after the logger middleware",
+			"method", r.Method, "path", r.URL.Path)
+	}
+
+	return false
+}
+
+// Collect collects the key-value log information and appends them by appendAttr.
+//
+// If the option attrorder is set, the collected attributes are appended
+// in the configured order, and the others are appended after them.
+func Collect(w http.ResponseWriter, r *http.Request, appendAttr func(...slog.Attr)) {
+	defer observecollect(time.Now())
+
+	c := getconfig(r)
+	if !c.matchstatus(w) {
+		return
+	}
+
+	order := c.AttrOrder
+	if len(order) == 0 && !c.GroupAttrs && collectHook == nil {
+		collect(c, w, r, appendAttr)
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 16)
+	collect(c, w, r, func(as ...slog.Attr) { attrs = append(attrs, as...) })
+	if len(order) > 0 {
+		attrs = sortattrs(attrs, order)
+	}
+	if c.GroupAttrs {
+		attrs = groupattrs(attrs)
+	}
+	if collectHook != nil {
+		collectHook(r, attrs)
+	}
+	appendAttr(attrs...)
+}
+
+var collectHook func(*http.Request, []slog.Attr)
+
+// SetCollectHook sets the hook to observe the attributes collected
+// by Collect before they are appended, such as to add them into the span.
+//
+// The hook must not modify or retain attrs, because the string values
+// of the bodies reference the buffers released by Release.
+func SetCollectHook(hook func(r *http.Request, attrs []slog.Attr)) {
+	collectHook = hook
+}
+
+// groupattrs moves the request and response attributes into the groups
+// request and response, which are appended after the other attributes.
+//
+// The attributes renamed by SetKeys are not grouped.
+func groupattrs(attrs []slog.Attr) []slog.Attr {
+	var reqattrs, respattrs []any
+	others := attrs[:0]
+	for _, attr := range attrs {
+		switch group, key := groupkey(attr.Key); group {
+		case "request":
+			reqattrs = append(reqattrs, slog.Attr{Key: key, Value: attr.Value})
+		case "response":
+			respattrs = append(respattrs, slog.Attr{Key: key, Value: attr.Value})
+		default:
+			others = append(others, attr)
+		}
+	}
+
+	if len(reqattrs) > 0 {
+		others = append(others, slog.Group("request", reqattrs...))
+	}
+	if len(respattrs) > 0 {
+		others = append(others, slog.Group("response", respattrs...))
+	}
+	return others
+}
+
+// groupkey returns the group and the key in the group of the attribute key,
+// such as ("request", "body") for "reqbody".
+func groupkey(key string) (group, name string) {
+	switch {
+	case key == "query", key == "cookies", key == "referer":
+		return "request", key
+	case key == "requestline":
+		return "request", "line"
+	case strings.HasPrefix(key, "reqheaders"):
+		return "request", key[3:]
+	case strings.HasPrefix(key, "reqbody"):
+		return "request", key[3:]
+	case strings.HasPrefix(key, "respheaders"):
+		return "response", key[4:]
+	case strings.HasPrefix(key, "respbody"):
+		return "response", key[4:]
+	default:
+		return "", key
+	}
+}
+
+// sortattrs sorts the attributes stably by the index of their keys in order.
+func sortattrs(attrs []slog.Attr, order []string) []slog.Attr {
+	rank := func(key string) int {
+		if index := slices.Index(order, key); index > -1 {
+			return index
+		}
+		return len(order)
+	}
+
+	slices.SortStableFunc(attrs, func(a, b slog.Attr) int {
+		return rank(a.Key) - rank(b.Key)
+	})
+	return attrs
+}
+
+func collect(c *Config, w http.ResponseWriter, r *http.Request, appendAttr func(...slog.Attr)) {
+	appendAttr = c.Keys.wrap(appendAttr)
+	if !checkwrapped(c, r) && c.StrictOrdering {
+		appendAttr(slog.Bool("loggerextmisconfigured", true))
+	}
+
+	if c.LogQuery {
+		appendAttr(slog.String("query", redactquery(c, r.URL.RawQuery)))
+	}
+
+	if c.LogRequestLine {
+		appendAttr(slog.String("requestline", r.Method+" "+getrequesturi(c, r)+" "+r.Proto))
+	}
+
+	if c.LogCurl {
+		appendAttr(slog.String("curl", curlcommand(c, r)))
+	}
+
+	slow := isslow(c, r)
+	if c.LogReqHeaders && slow {
+		appendAttr(slog.Any("reqheaders", redactheaders(c, r.Header)))
+	}
+
+	if c.LogCookies && slow {
+		if attr, ok := getcookiesattr(c, r); ok {
+			appendAttr(attr)
+		}
+	}
+
+	if c.LogRespHeaders && slow {
+		appendAttr(slog.Any("respheaders", redactheaders(c, w.Header())))
+	}
+
+	if c.LogTrailers {
+		appendtrailers(c, w, r, appendAttr)
+	}
+
+	if r.Method == http.MethodConnect {
+		// Only log the metadata of the tunnel, including the extended CONNECT.
+		protocol := r.Header.Get(":protocol")
+		if protocol == "" {
+			protocol = r.Proto
+		}
+		appendAttr(slog.String("authority", r.Host), slog.String("protocol", protocol))
+	}
+
+	if route := getroute(r); route != "" {
+		appendAttr(slog.String("route", route))
+	}
+
+	appendtraceattrs(r, appendAttr)
+
+	if c.LogClientIP {
+		appendAttr(slog.String("client_ip", getclientip(c, r)))
+	}
+
+	if c.LogBasicUser {
+		if user, _, ok := r.BasicAuth(); ok && user != "" {
+			appendAttr(slog.String("user", user))
+		}
+	}
+
+	if len(c.JWTClaims) > 0 {
+		if attr, ok := getjwtattr(c, r); ok {
+			appendAttr(attr)
+		}
+	}
+
+	if identityExtractor != nil {
+		if attrs := identityExtractor(r); len(attrs) > 0 {
+			appendAttr(attrs...)
+		}
+	}
+
+	if service, ok := getservice(c, r.URL.Path); ok {
+		appendAttr(slog.String("service", service))
+	}
+
+	if ispushed(c, r) {
+		appendAttr(slog.Bool("pushed", true))
+	}
+
+	appendtlsattrs(c, r, appendAttr)
+
+	if errorGetter != nil {
+		if err := errorGetter(r); err != nil {
+			appendAttr(slog.Any("herr", err))
+		}
+	}
+
+	if c.LogReferer {
+		if referer := r.Referer(); referer != "" {
+			appendAttr(slog.String("referer", getreferer(c, referer)))
+		}
+	}
+
+	rw := getResponseWriter(w)
+	if c.LogStatus && rw != nil {
+		status, written := rw.getwritten()
+		appendAttr(slog.Int("status", status), slog.Int("respwritten", written))
+	}
+
+	// For bodyonerror, only log the body contents of the failed request.
+	logcontent := !c.BodyOnError || (rw != nil && rw.getstatus() >= 400)
+	logcontent = logcontent && slow && !(c.BodyHash && c.BodyHashOnly)
+
+	var violations []string
+	only, _ := r.Context().Value(recordonlykey).(recordonly)
+	if reqbody, ok := r.Context().Value(reqbodykey).(*reqbody); ok && !only.req {
+		c := c.forct(reqbody.ct)
+		size := reqbody.size()
+		reqsizes.add(size)
+		if reqbody.multipart != nil {
+			appendAttr(slog.Int("reqbodylen", size))
+			if logcontent {
+				appendAttr(reqbody.multipart.attr("reqbody"))
+			}
+		} else if data, dsize, ok := reqbody.decode(); ok {
+			// Log the length of the compressed body, and the decompressed body.
+			appendAttr(slog.Int("reqbodylen", size), slog.Int("reqbodydecompressedlen", dsize))
+			if logcontent {
+				data, dsize = transcodebody(r.Header, data, dsize)
+				appendbodycontent(c, appendAttr, r, "request", reqbody.ct, data, dsize, nil)
+			}
+		} else {
+			appendAttr(slog.Int("reqbodylen", size))
+			if logcontent {
+				tail := reqbody.gettail(size)
+				data, size := transcodebody(r.Header, reqbody.data, size)
+				appendbodycontent(c, appendAttr, r, "request", reqbody.ct, data, size, tail)
+			}
+		}
+
+		if sum, ok := reqbody.sum(); ok {
+			appendAttr(slog.String("reqbodysha256", sum))
+		}
+
+		if reqbody.toolarge {
+			appendAttr(slog.Bool("reqbodytoolarge", true))
+		}
+
+		if reqbody.err != nil {
+			appendAttr(slog.String("reqbodyreaderr", reqbody.err.Error()))
+		}
+
+		reqbody.spill.appendattrs(appendAttr, "reqbody", reqbody.done && reqbody.err == nil)
+
+		// The whole body has been read, but its length is not Content-Length.
+		if reqbody.done && r.ContentLength >= 0 && int64(size) != r.ContentLength && reqbody.buf != nil {
+			appendAttr(slog.Bool("reqbodylenmismatch", true))
+		}
+
+		// Only the whole body read by the handler can be validated.
+		if bodyValidator != nil && reqbody.multipart == nil && reqbody.done && reqbody.err == nil {
+			data := reqbody.data
+			if ddata, dsize, ok := reqbody.decode(); ok {
+				data, size = ddata, dsize
+			}
+			violations = validatebody(r, "request", 0, r.Header, data, size)
+		}
+	}
+
+	if c.LogReqBody && logcontent && r.Method == http.MethodGet &&
+		len(c.GraphQLPaths) > 0 && c.isgraphql(r.URL.Path) {
+		if attr, ok := getgraphqlqueryattr(c, r); ok {
+			appendAttr(attr)
+		}
+	}
+
+	if rw != nil && !only.resp {
+		if data, size, ok := rw.snapshot(); ok {
+			ct := getpathct(resppathcts, r.URL.Path, w.Header())
+			c := c.forct(ct)
+
+			respsizes.add(size)
+			appendAttr(slog.Int("respbodylen", size))
+			encoding := w.Header().Get("Content-Encoding")
+			if encoding != "" {
+				// The body is compressed, such as by the inner compression middleware.
+				if ddata, dsize, ok := rw.decode(data, size, encoding, c.BodyMaxLen); ok {
+					appendAttr(slog.Int("respbodydecompressedlen", dsize))
+					data, size, encoding = ddata, dsize, ""
+				}
+			}
+			if encoding == "" && rw.skipreason() == "" {
+				violations = append(violations, validatebody(r, "response", rw.getstatus(), w.Header(), data, size)...)
+			}
+			data, size = transcodebody(w.Header(), data, size)
+			data, size = compactjsonbody(c, ct, data, size)
+
+			switch skipped := rw.skipreason(); {
+			case skipped != "":
+				appendAttr(slog.String("respbody_skipped", skipped))
+
+			case !logcontent:
+
+			case size == 0:
+				if attr, ok := getemptybodyattr(c, "respbody"); ok {
+					appendAttr(attr)
+				}
+
+			case rw.logbody && appenddecodedbody(c, appendAttr, r, "response", "respbody", ct, data, size):
+				// The body has been decoded and appended as JSON.
+
+			case rw.logbody && c.NDJSONLines > 0 && isndjsonct(ct):
+				appendndjsonbody(c, appendAttr, "respbody", data, size)
+
+			case rw.logbody && isbinaryct(c, ct):
+				appendAttr(getbinarysnippetattr(c, data, "respbody"))
+
+			case rw.logbody && len(data) < size && containsct(c, ct) && rw.isstreamed():
+				// Log the buffered head of the stream.
+				appendtruncatedbody(c, appendAttr, "respbody", ct, data, size, rw.gettail(size))
+
+			case rw.logbody && shouldlogbody(c, r, "response", ct, size):
+				if attr, ok := getbodyattr(c, data, "respbody", ct); ok {
+					appendAttr(attr)
+				}
+
+			case rw.logbody && shouldtruncatebody(c, ct, size):
+				appendtruncatedbody(c, appendAttr, "respbody", ct, data, size, rw.gettail(size))
+
+			case rw.errbody && rw.getstatus() >= 400 && size <= c.ErrorBodyMaxLen:
+				// Log the small error body regardless of the content type.
+				if attr, ok := getbodyattr(c, data, "respbody", ct); ok {
+					appendAttr(attr)
+				}
+			}
+
+			if sum, ok := rw.sum(); ok {
+				appendAttr(slog.String("respbodysha256", sum))
+			}
+
+			// The body of the partial content is only a part of the resource.
+			if crange := w.Header().Get("Content-Range"); crange != "" {
+				appendAttr(slog.String("respbodyrange", crange))
+			}
+
+			rw.appendspillattrs(appendAttr)
+		}
+	}
+
+	if len(violations) > 0 {
+		appendAttr(slog.Any("schema_violations", violations))
+	}
+
+	publish(c, w, r)
+	record(c, w, r)
+	capture(c, r)
+}
+
+// isslow reports whether the request has taken longer than the option
+// slowthreshold since it was wrapped by WrapReqRespBody.
+//
+// If the threshold is disabled or the start time is unknown, return true.
+func isslow(c *Config, r *http.Request) bool {
+	if c.SlowThreshold <= 0 {
+		return true
+	}
+
+	start, ok := r.Context().Value(startkey).(time.Time)
+	return !ok || time.Since(start) >= c.SlowThreshold
+}
+
+func getreferer(c *Config, referer string) string {
+	if !c.LogRefererPathOnly {
+		return referer
+	}
+
+	if u, err := url.Parse(referer); err == nil {
+		return u.EscapedPath()
+	}
+
+	// Strip the query and fragment of the invalid referer.
+	if index := strings.IndexAny(referer, "?#"); index > -1 {
+		referer = referer[:index]
+	}
+	return referer
+}
+
+// appendbodyattrs appends the attributes of the body length and content,
+// which may be only a part of the whole body with the length size.
+//
+// direction is either "request" or "response".
+func appendbodyattrs(c *Config, appendAttr func(...slog.Attr), r *http.Request, direction, ct string, data []byte, size int) {
+	key := "reqbody"
+	if direction == "response" {
+		key = "respbody"
+	}
+
+	appendAttr(slog.Int(key+"len", size))
+	appendbodycontent(c, appendAttr, r, direction, ct, data, size, nil)
+}
+
+// appendbodycontent appends the attribute of the body content,
+// and tail is the last bytes of the whole body if not nil.
+func appendbodycontent(c *Config, appendAttr func(...slog.Attr), r *http.Request,
+	direction, ct string, data []byte, size int, tail []byte) {
+	data, size = compactjsonbody(c, ct, data, size)
+
+	key := "reqbody"
+	if direction == "response" {
+		key = "respbody"
+	} else if len(c.GraphQLPaths) > 0 && c.isgraphql(r.URL.Path) {
+		// Log the GraphQL operation instead of the raw body if parsed.
+		if attr, ok := getgraphqlbodyattr(c, data); ok {
+			appendAttr(attr)
+			return
+		}
+	}
+
+	switch {
+	case size == 0:
+		if attr, ok := getemptybodyattr(c, key); ok {
+			appendAttr(attr)
+		}
+
+	case appenddecodedbody(c, appendAttr, r, direction, key, ct, data, size):
+		// The body has been decoded and appended as JSON.
+
+	case c.NDJSONLines > 0 && isndjsonct(ct):
+		appendndjsonbody(c, appendAttr, key, data, size)
+
+	case isbinaryct(c, ct):
+		if len(data) > 0 {
+			appendAttr(getbinarysnippetattr(c, data, key))
+		}
+
+	case shouldlogbody(c, r, direction, ct, size):
+		if attr, ok := getbodyattr(c, data, key, ct); ok {
+			appendAttr(attr)
+		}
+
+	case shouldtruncatebody(c, ct, size):
+		appendtruncatedbody(c, appendAttr, key, ct, data, size, tail)
+	}
+}
+
+// shouldtruncatebody reports whether the oversized body should be logged
+// with its first bodymaxlen bytes.
+func shouldtruncatebody(c *Config, ct string, size int) bool {
+	return c.TruncateBody && c.BodyMaxLen > 0 && size > c.BodyMaxLen && containsct(c, ct)
+}
+
+func appendtruncatedbody(c *Config, appendAttr func(...slog.Attr), key, ct string, data []byte, size int, tail []byte) {
+	if len(data) == 0 {
+		return // The body is not buffered.
+	}
+
+	// The fields of the truncated JSON or XML cannot be filtered or redacted.
+	if isjsonct(ct) && (len(c.BodyFields) > 0 || len(c.RedactFields) > 0) {
+		return
+	}
+	if isxmlct(ct) && len(c.RedactXML) > 0 {
+		return
+	}
+	if isformct(ct) && c.FormBody && len(c.RedactQueries) > 0 {
+		return
+	}
+
+	if last := gettruncatedtail(c, size, tail); last != nil {
+		data = joinheadtail(data[:min(len(data), c.BodyMaxLen-len(last))], last, size)
+	} else if c.BodyMaxLen > 0 {
+		data = data[:min(len(data), c.BodyMaxLen)]
+	}
+	if attr, ok := getbodyattr(c, data, key, ""); ok {
+		appendAttr(attr, slog.Bool(key+"truncated", true))
+	}
+}
+
+func getemptybodyattr(c *Config, key string) (attr slog.Attr, ok bool) {
+	switch c.EmptyBody {
+	case "empty-string":
+		return slog.String(key, ""), true
+	case "null":
+		return slog.Any(key, nil), true
+	default:
+		return
+	}
+}
+
+var oversizeHandler func(r *http.Request, direction string, size int)
+
+// SetOversizeHandler sets the handler called when the request or response
+// body exceeds the maximum length to log, which may be used to count metrics
+// or alert.
+//
+// direction is either "request" or "response". For the outbound request
+// logged by NewLoggingTransport, r is the client request.
+func SetOversizeHandler(handler func(r *http.Request, direction string, size int)) {
+	oversizeHandler = handler
+}
+
+func shouldlogbody(c *Config, r *http.Request, direction, ct string, datalen int) bool {
+	if maxlen := c.BodyMaxLen; maxlen > 0 && datalen > maxlen {
+		if oversizeHandler != nil {
+			oversizeHandler(r, direction, datalen)
+		}
+		metrics.skippedsize.Add(1)
+		return false
+	}
+
+	if !containsct(c, ct) {
+		metrics.skippedtype.Add(1)
+		return false
+	}
+	return true
+}
+
+// getbodyattr returns the attribute of the body content.
+//
+// If the body should not be logged, such as being filtered out, ok is false.
+func getbodyattr(c *Config, data []byte, key, ct string) (attr slog.Attr, ok bool) {
+	buffered := data
+	data = scrubbody(c, ct, data)
+	if isjsonct(ct) {
+		if fields := c.BodyFields; len(fields) > 0 {
+			// Only log the allowed fields of the JSON object.
+			if data = filterjsonfields(data, fields); data == nil {
+				return
+			}
+		}
+
+		if trimmed := bytes.TrimLeft(data, jsonspace); len(trimmed) > 0 {
+			if paths := c.RedactFields; len(paths) > 0 {
+				var err error
+				if trimmed, err = redactjsonfields(trimmed, paths); err != nil {
+					// Not log the body which cannot be redacted to avoid leaking.
+					return
+				}
+				data = trimmed
+			}
+		}
+
+		if data := bytes.TrimLeft(data, jsonspace); len(data) > 0 && (data[0] == '{' || data[0] == '[') {
+			if c.PrettyBody {
+				if indented, err := indentjson(validutf8(data)); err == nil {
+					return slog.String(key, indented), true
+				}
+			}
+			return slog.Any(key, rawjson.Bytes(safebytes(c, validutf8(data), buffered))), true
+		}
+	}
+
+	if c.FormBody && isformct(ct) {
+		if formattr, err := getformattr(c, data, key); err == nil {
+			return formattr, true
+		} else if len(c.RedactQueries) > 0 {
+			// Not log the body which cannot be redacted to avoid leaking.
+			return
+		}
+	}
+
+	if isxmlct(ct) && (c.XMLCompact || c.PrettyBody || len(c.RedactXML) > 0) {
+		if _data, err := transformxml(data, c.RedactXML, c.XMLCompact, prettyindent(c)); err == nil {
+			data = _data
+		} else if len(c.RedactXML) > 0 {
+			// Not log the body which cannot be redacted to avoid leaking.
+			return
+		}
+	}
+
+	if isbinary(c, data) {
+		// The body is mislabeled as text, so log it as base64.
+		return slog.Group(key, slog.Bool("binary", true),
+			slog.String("base64", base64.StdEncoding.EncodeToString(data))), true
 	}
+
+	data = safebytes(c, validutf8(data), buffered)
+	return slog.String(key, unsafe.String(unsafe.SliceData(data), len(data))), true
 }
 
-// Enabled reports whether to log the request.
-func Enabled(req *http.Request) bool {
-	if req.URL.Path == "/" {
-		return false
+// prettyIndent is the indent of the JSON and XML bodies by the option prettybody.
+const prettyIndent = "  "
+
+func prettyindent(c *Config) string {
+	if c.PrettyBody {
+		return prettyIndent
 	}
-	return !isignore(req.URL.Path)
+	return ""
 }
 
-// Collect collects the key-value log information and appends them by appendAttr.
-func Collect(w http.ResponseWriter, r *http.Request, appendAttr func(...slog.Attr)) {
-	if logQuery.Get() {
-		appendAttr(slog.String("query", r.URL.RawQuery))
+// safebytes returns the copy of data if it references the buffered body,
+// which may be reused after Release, unless the option zerocopy is enabled.
+func safebytes(c *Config, data, buffered []byte) []byte {
+	if c.ZeroCopy || len(data) == 0 || cap(buffered) == 0 {
+		return data
 	}
 
-	if logReqHeaders.Get() {
-		appendAttr(slog.Any("reqheaders", r.Header))
+	// data may be the sub-slice of the buffered body, such as being trimmed.
+	start := uintptr(unsafe.Pointer(unsafe.SliceData(buffered)))
+	if p := uintptr(unsafe.Pointer(unsafe.SliceData(data))); p < start || p >= start+uintptr(cap(buffered)) {
+		return data
 	}
+	return bytes.Clone(data)
+}
 
-	if logRespHeaders.Get() {
-		appendAttr(slog.Any("respheaders", w.Header()))
+// isbinary reports whether the ratio of the invalid UTF-8 bytes
+// in data exceeds the option binarythreshold.
+func isbinary(c *Config, data []byte) bool {
+	if utf8.Valid(data) {
+		return false
 	}
 
-	if reqbody, ok := r.Context().Value(reqbodykey).(reqbody); ok {
-		appendAttr(slog.Int("reqbodylen", len(reqbody.data)))
-		if shouldlogbody(reqbody.ct, len(reqbody.data)) {
-			appendAttr(getbodyattr(reqbody.data, "reqbody", reqbody.ct))
-		}
+	threshold := c.BinaryThreshold
+	if threshold <= 0 {
+		return false
 	}
 
-	if rw := getResponseWriter(w); rw != nil {
-		_len := rw.buf.Len()
-		appendAttr(slog.Int("respbodylen", _len))
-		if ct := getContentType(w.Header()); shouldlogbody(ct, _len) {
-			appendAttr(getbodyattr(rw.buf.Bytes(), "respbody", ct))
+	var invalid int
+	for i := 0; i < len(data); {
+		r, n := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && n == 1 {
+			invalid++
 		}
+		i += n
 	}
+
+	return float64(invalid)/float64(len(data)) > threshold
 }
 
-func shouldlogbody(ct string, datalen int) bool {
-	if maxlen := logBodyMaxLen.Get(); maxlen > 0 && datalen > maxlen {
-		return false
-	}
-	return containsct(ct)
+// isbinaryct reports whether ct is the binary content type
+// configured by the option binarytypes.
+func isbinaryct(c *Config, ct string) bool {
+	return len(c.BinaryTypes) > 0 && matchct(ct, c.BinaryTypes)
 }
 
-func getbodyattr(data []byte, key, ct string) slog.Attr {
-	if strings.HasSuffix(ct, "json") && len(data) > 0 && (data[0] == '{' || data[0] == '[') {
-		return slog.Any(key, rawjson.Bytes(data))
+// getbinarysnippetattr returns the attribute of the prefix of the binary body,
+// which is encoded by the option binaryencoding.
+func getbinarysnippetattr(c *Config, data []byte, key string) slog.Attr {
+	data = data[:min(len(data), c.BinarySnippetLen)]
+
+	var snippet string
+	if c.BinaryEncoding == "hex" {
+		snippet = hex.EncodeToString(data)
+	} else {
+		snippet = base64.StdEncoding.EncodeToString(data)
 	}
-	return slog.String(key, unsafe.String(unsafe.SliceData(data), len(data)))
+	return slog.Group(key, slog.Bool("binary", true), slog.String(c.BinaryEncoding, snippet))
+}
+
+// isjsonct reports whether ct is the content type of a single JSON value,
+// such as application/json and application/problem+json,
+// but not the newline-delimited JSON.
+func isjsonct(ct string) bool {
+	return strings.HasSuffix(ct, "json") && !strings.HasSuffix(ct, "ndjson")
 }
 
 func getContentType(header http.Header) (mime string) {
@@ -182,8 +1369,30 @@ func getContentType(header http.Header) (mime string) {
 	return
 }
 
-func containsct(ct string) bool {
-	cts := logBodyTypes.Get()
+// commonTextTypes is the common text or structured content types,
+// which are included when the option includetexttypes is enabled.
+var commonTextTypes = []string{
+	"text/*",
+	"application/json", "*+json", "application/x-ndjson",
+	"application/xml", "*+xml",
+	"application/x-www-form-urlencoded",
+	"application/javascript", "application/graphql",
+	"application/yaml", "application/x-yaml",
+}
+
+func containsct(c *Config, ct string) bool {
+	if maxlen, ok := c.ctbodymaxlen(ct); ok && maxlen == 0 {
+		return false
+	}
+
+	if matchct(ct, c.BodyTypes) || isbinaryct(c, ct) || (c.LogXML && isxmlct(ct)) ||
+		(c.NDJSONLines > 0 && isndjsonct(ct)) || ctdecoders[ct] != nil {
+		return true
+	}
+	return c.IncludeTextTypes && matchct(ct, commonTextTypes)
+}
+
+func matchct(ct string, cts []string) bool {
 	for _, _ct := range cts {
 		if _ct == "" {
 			continue
@@ -217,39 +1426,198 @@ func containsct(ct string) bool {
 //
 // NOTICE: Release should be called after handling the request.
 func WrapReqRespBody(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request) {
-	w, r = wrapRequestBody(w, r)
-	w, r = wrapResponseBody(w, r)
+	if loggingDisabled(r.Context()) || r.Method == http.MethodConnect || isupgrade(r.Header) {
+		// For CONNECT and the protocol upgrade, such as WebSocket,
+		// the bodies are the tunneled stream, which must not be buffered.
+		return w, r
+	}
+
+	c := getconfig(r)
+	if c.isskipped(r.Header) && !c.isforced(r.Header) {
+		return w, r
+	}
+
+	// Resolve the configuration only once, which is reused by Collect.
+	r = r.WithContext(context.WithValue(r.Context(), resolvedkey, c))
+
+	if c.wrapenabled() {
+		r = r.WithContext(context.WithValue(r.Context(), wrappedkey, true))
+	}
+	if c.SlowThreshold > 0 || c.FlightRecorder > 0 {
+		r = r.WithContext(context.WithValue(r.Context(), startkey, time.Now()))
+	}
+	if !issampled(c, r) {
+		// Only the bodies forced by the context are logged.
+		_c := *c
+		_c.LogReqBody, _c.LogRespBody, _c.LogErrorBodies = false, false, false
+		c = &_c
+	}
+
+	captured := iscaptured(c)
+	if captured {
+		r = r.WithContext(context.WithValue(r.Context(), capturekey, true))
+	}
+
+	recordreq := c.FlightRecorder > 0 || captured
+	recordresp := c.FlightRecorder > 0
+	if (recordreq && !c.LogReqBody) || (recordresp && !c.LogRespBody) {
+		// Capture the bodies only for the flight recorder or capturedir, but not log them.
+		only := recordonly{req: recordreq && !c.LogReqBody, resp: recordresp && !c.LogRespBody}
+		r = r.WithContext(context.WithValue(r.Context(), recordonlykey, only))
+
+		_c := *c
+		_c.LogReqBody = c.LogReqBody || recordreq
+		_c.LogRespBody = c.LogRespBody || recordresp
+		c = &_c
+	}
+
+	w, r = wrapRequestBody(c, w, r)
+	w, r = wrapResponseBody(c, w, r)
 	return w, r
 }
 
 // Release tries to release the buffer into the pool.
 func Release(w http.ResponseWriter, r *http.Request) {
-	if reqbody, ok := r.Context().Value(reqbodykey).(reqbody); ok {
-		putbuffer(reqbody.buf)
+	if reqbody, ok := r.Context().Value(reqbodykey).(*reqbody); ok {
+		reqbody.release()
+	}
+	if rw := getResponseWriter(w); rw != nil {
+		if buf := rw.detach(); buf != nil {
+			putbuffer(buf)
+		}
+	}
+}
+
+// RequestBody returns the request body buffered by WrapReqRespBody,
+// which may be only the first bodymaxlen+1 bytes of the whole body.
+//
+// NOTICE: the returned bytes are valid only until Release is called,
+// so they must not be modified or retained.
+func RequestBody(r *http.Request) (data []byte, ok bool) {
+	if reqbody, _ok := r.Context().Value(reqbodykey).(*reqbody); _ok && reqbody.buf != nil {
+		data, ok = reqbody.data, true
 	}
+	return
+}
+
+// ResponseBody returns the response body buffered by WrapReqRespBody,
+// which will unwrap w to find the wrapped response writer.
+//
+// NOTICE: the returned bytes are valid only until Release is called,
+// so they must not be modified or retained.
+func ResponseBody(w http.ResponseWriter) (data []byte, ok bool) {
 	if rw := getResponseWriter(w); rw != nil {
-		putbuffer(rw.buf)
+		data, _, ok = rw.snapshot()
 	}
+	return
 }
 
 /// ----------------------------------------------------------------------- ///
 
-func wrapRequestBody(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request) {
-	if !logReqBody.Get() {
+// ismutating reports whether the request method is non-idempotent,
+// that's, POST, PUT, PATCH or DELETE.
+func ismutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// logbodyfor reports whether to log the body of the request
+// with the method by the option mutatingbodyonly.
+func logbodyfor(c *Config, method string) bool {
+	return !c.MutatingBodyOnly || ismutating(method)
+}
+
+func wrapRequestBody(c *Config, w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request) {
+	log := c.LogReqBody && logbodyfor(c, r.Method)
+	if _log, ok := logReqFromContext(r.Context()); ok {
+		log = _log
+	}
+	if !log {
 		return w, r
 	}
 
-	reqbody := reqbody{ct: getContentType(r.Header)}
-	if containsct(reqbody.ct) {
+	ct := getpathct(reqpathcts, r.URL.Path, r.Header)
+	c = c.forct(ct)
+
+	reqbody := &reqbody{
+		ct:       ct,
+		encoding: r.Header.Get("Content-Encoding"),
+		maxlen:   c.BodyMaxLen,
+		spill:    newspillfile(c).forct(ct),
+		tail:     newtailbuf(c),
+	}
+	if c.BodyHash {
+		reqbody.hash = sha256.New()
+	}
+	maxlen := c.BodyMaxLen
+	boundary, ismultipart := getmultipartboundary(r.Header)
+	switch {
+	case ismultipart && c.MultipartMeta:
+		// Only log the metadata of the parts, but never the contents.
+		reqbody.multipart = newmultipartmeta(boundary)
+		metrics.reqcaptured.Add(1)
+		r.Body = &multipartBody{Closer: r.Body, body: r.Body, reqbody: reqbody}
+		r = r.WithContext(context.WithValue(r.Context(), reqbodykey, reqbody))
+
+	case !containsct(c, reqbody.ct):
+		metrics.skippedtype.Add(1)
+		if r.ContentLength == 0 && c.EmptyBody != "omit" {
+			// Record the empty body without buffering to represent it consistently.
+			r = r.WithContext(context.WithValue(r.Context(), reqbodykey, reqbody))
+		}
+
+	case maxlen > 0 && r.ContentLength > int64(maxlen) && !c.TruncateBody && !c.BodyHash &&
+		reqbody.spill == nil && !isbinaryct(c, reqbody.ct):
+		// The body is known to be too large to be logged, so not buffer it.
+		reqbody.toolarge, reqbody.clen = true, int(r.ContentLength)
+		metrics.skippedsize.Add(1)
+		r = r.WithContext(context.WithValue(r.Context(), reqbodykey, reqbody))
+
+	case c.LazyReqBody:
+		// Capture the body only when the handler reads it.
+		reqbody.buf = getbuffer()
+		metrics.reqcaptured.Add(1)
+		r.Body = &lazyBody{
+			Closer:  r.Body,
+			body:    r.Body,
+			limit:   maxlen + 1,
+			reqbody: reqbody,
+		}
+		r = r.WithContext(context.WithValue(r.Context(), reqbodykey, reqbody))
+
+	default:
+		// Only read at most maxlen+1 bytes for logging, which is enough
+		// to know whether the body is too large to be logged.
+		var body io.Reader = r.Body
+		if maxlen > 0 {
+			body = io.LimitReader(r.Body, int64(maxlen)+1)
+		}
+
 		reqbody.buf = getbuffer()
-		_, err := io.CopyBuffer(reqbody.buf, r.Body, make([]byte, 512))
+		metrics.reqcaptured.Add(1)
+		n, err := reqbody.buf.ReadFrom(body)
+		if maxlen <= 0 || n <= int64(maxlen) {
+			reqbody.done = true // Reach EOF or fail.
+		}
 		if err != nil {
+			reqbody.err = err
 			slog.Error("fail to read the request body", "raddr", r.RemoteAddr,
-				"method", r.Method, "path", r.RequestURI, "err", err)
+				"method", r.Method, "path", getrequesturi(c, r), "err", err)
 		}
 
 		reqbody.data = reqbody.buf.Bytes()
-		r.Body = io.NopCloser(reqbody.buf)
+		reqbody.hashwrite(reqbody.data)
+		reqbody.tail.write(reqbody.data)
+		r.Body = &teeBody{
+			Closer:  r.Body,
+			data:    bytes.NewReader(reqbody.data),
+			body:    r.Body,
+			reqbody: reqbody,
+		}
 
 		r = r.WithContext(context.WithValue(r.Context(), reqbodykey, reqbody))
 	}
@@ -258,6 +1626,7 @@ func wrapRequestBody(w http.ResponseWriter, r *http.Request) (http.ResponseWrite
 }
 
 var (
+	wrappedkey  = contextkey{key: "wrappedkey"}
 	reqbodykey  = contextkey{key: "reqbodykey"}
 	respbodykey = contextkey{key: "respbodykey"}
 )
@@ -267,21 +1636,211 @@ type reqbody struct {
 	data []byte
 	buf  *bytes.Buffer
 	ct   string
+	rest int   // The number of the bytes read by the handler after data.
+	err  error // The error occurring when reading the original body.
+	done bool  // Whether the original body has been read to EOF or failed.
+
+	toolarge bool // Whether the body is not buffered since Content-Length is too large.
+	clen     int  // The Content-Length of the too large body.
+
+	encoding string
+	decoded  *bytes.Buffer
+	dsize    int
+	maxlen   int
+
+	multipart *multipartmeta
+	hash      hash.Hash  // The SHA-256 hash of the whole body if not nil.
+	spill     *spillfile // Spill the body larger than maxlen if not nil.
+	tail      *tailbuf   // Keep the last bytes of the body if not nil.
+}
+
+// gettail returns the last bytes of the body with the length size
+// if it has been read wholly.
+func (b *reqbody) gettail(size int) []byte {
+	if !b.done || b.err != nil {
+		return nil
+	}
+	return b.tail.last(size)
+}
+
+// hashwrite writes the read body data into the hash if enabled.
+func (b *reqbody) hashwrite(p []byte) {
+	if b.hash != nil {
+		b.hash.Write(p)
+	}
+}
+
+// sum returns the hex-encoded SHA-256 hash of the body,
+// which is only available when the whole body has been read successfully.
+func (b *reqbody) sum() (sum string, ok bool) {
+	if b.hash == nil || !b.done || b.err != nil {
+		return
+	}
+	return hex.EncodeToString(b.hash.Sum(nil)), true
+}
+
+// whole reports whether the whole body has been buffered without error.
+func (b *reqbody) whole(maxlen int) bool {
+	return b.buf != nil && !b.toolarge && b.rest == 0 && b.err == nil &&
+		(maxlen <= 0 || len(b.data) <= maxlen)
+}
+
+// size returns the size of the request body that has been read.
+func (b *reqbody) size() int {
+	if b.toolarge {
+		return b.clen
+	}
+	return len(b.data) + b.rest
+}
+
+// decode decompresses the whole compressed body only once,
+// and returns the decompressed body and its length.
+//
+// If the body is not compressed or fails to be decompressed, ok is false.
+func (b *reqbody) decode() (data []byte, size int, ok bool) {
+	if b.decoded == nil {
+		if b.encoding == "" || b.rest > 0 || len(b.data) == 0 {
+			return
+		}
+
+		var limit int
+		if b.maxlen > 0 {
+			limit = b.maxlen + 1
+		}
+
+		buf := getbuffer()
+		dsize, err := decodebody(buf, b.data, b.encoding, limit)
+		if err != nil {
+			putbuffer(buf)
+			return
+		}
+		b.decoded, b.dsize = buf, dsize
+	}
+	return b.decoded.Bytes(), b.dsize, true
+}
+
+func (b *reqbody) release() {
+	if b.multipart != nil {
+		b.multipart.finish()
+	}
+	if b.buf != nil {
+		putbuffer(b.buf)
+		b.buf, b.data = nil, nil
+	}
+	if b.decoded != nil {
+		putbuffer(b.decoded)
+		b.decoded = nil
+	}
+	b.spill.close()
+}
+
+// teeBody is used to replace the original request body, which reads
+// the data read for logging first, then the rest of the original body.
+type teeBody struct {
+	io.Closer
+	data    *bytes.Reader
+	body    io.Reader
+	reqbody *reqbody
+}
+
+func (b *teeBody) Read(p []byte) (n int, err error) {
+	if b.data.Len() > 0 {
+		return b.data.Read(p)
+	}
+
+	n, err = b.body.Read(p)
+	b.reqbody.rest += n
+	b.reqbody.hashwrite(p[:n])
+	b.reqbody.tail.write(p[:n])
+	if n > 0 && b.reqbody.spill != nil {
+		b.reqbody.spill.write(b.reqbody.data, p[:n])
+	}
+	if err != nil {
+		b.reqbody.done = true
+		if err != io.EOF {
+			b.reqbody.err = err
+		}
+	}
+	return
+}
+
+// lazyBody is used to replace the original request body, which captures
+// at most limit bytes of the body as the handler reads it.
+type lazyBody struct {
+	io.Closer
+	body    io.Reader
+	limit   int // No limit if less than or equal to 1.
+	reqbody *reqbody
+}
+
+func (b *lazyBody) Read(p []byte) (n int, err error) {
+	n, err = b.body.Read(p)
+	b.reqbody.hashwrite(p[:n])
+	b.reqbody.tail.write(p[:n])
+	if buf := b.reqbody.buf; buf != nil && n > 0 {
+		captured := n
+		if b.limit > 1 {
+			captured = min(n, max(b.limit-buf.Len(), 0))
+		}
+
+		if captured > 0 {
+			buf.Write(p[:captured])
+			b.reqbody.data = buf.Bytes()
+		}
+		b.reqbody.rest += n - captured
+
+		if n > captured && b.reqbody.spill != nil {
+			b.reqbody.spill.write(buf.Bytes(), p[captured:n])
+		}
+	}
+
+	if err != nil {
+		b.reqbody.done = true
+		if err != io.EOF {
+			b.reqbody.err = err
+		}
+	}
+	return
 }
 
 /// ----------------------------------------------------------------------- ///
 
-func wrapResponseBody(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request) {
-	if !logRespBody.Get() {
-		return w, r
+func wrapResponseBody(c *Config, w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request) {
+	logbody, errbody := c.LogRespBody && logbodyfor(c, r.Method), c.LogErrorBodies
+	if log, ok := logRespFromContext(r.Context()); ok {
+		if !log {
+			return w, r
+		}
+		logbody = true
+	}
+
+	if logbody && acceptseventstream(r.Header) {
+		// The response is the infinite stream of the server-sent events.
+		logbody = false
 	}
 
-	if log, ok := logRespFromContext(r.Context()); ok && !log {
+	if !logbody && !errbody && !c.BodyOnError && len(c.Statuses) == 0 && !c.LogStatus {
 		return w, r
 	}
 
-	buf := getbuffer()
-	w = newResponseWriter(w, buf)
+	rw := newResponseWriter(w, logbody, errbody, c.ErrorBodyMaxLen)
+	if logbody && c.BodyHash {
+		rw.hash = sha256.New()
+	}
+	if logbody {
+		// Keep the head enough for the largest bodymaxlen of the content types.
+		_c := *c
+		_c.BodyMaxLen = c.maxbodymaxlen()
+		rw.spill, rw.path = newspillfile(&_c), r.URL.Path
+	}
+	rw.budget = c.BodyBudget
+	if logbody {
+		rw.streammaxlen, rw.tail = c.StreamMaxLen, newtailbuf(c)
+	}
+	if logbody && c.NDJSONLines > 0 {
+		rw.ndjsonlines, rw.ndjsonmaxlen, rw.path = c.NDJSONLines, c.BodyMaxLen, r.URL.Path
+	}
+	w = rw
 	r = r.WithContext(context.WithValue(r.Context(), respbodykey, w))
 
 	return w, r
@@ -302,27 +1861,364 @@ func getResponseWriter(w http.ResponseWriter) *responseWriter {
 	}
 }
 
+// responseWriter copies the written response body into buf.
+//
+// Whether to buffer the body is decided when the status code is written,
+// so the body of the error response may be buffered separately.
+//
+// The handler may write the response in other goroutines which outlive it,
+// so the access of buf is protected by the mutex, and buf is detached
+// when released so that the late writes do not corrupt the pooled buffer.
 type responseWriter struct {
 	http.ResponseWriter
-	buf *bytes.Buffer
+
+	lock     sync.Mutex
+	buf      *bytes.Buffer
+	limit    int // The maximum length of buf. 0 means no limit.
+	size     int // The total length of the written body.
+	status   int
+	released bool
+
+	decoded *bytes.Buffer
+	dsize   int
+	hash    hash.Hash  // The SHA-256 hash of the whole body if not nil.
+	spill   *spillfile // Spill the body larger than bodymaxlen if not nil.
+	tail    *tailbuf   // Keep the last bytes of the body if not nil.
+
+	budget     int  // The global body budget. 0 means no limit.
+	reserved   int  // The bytes reserved from the global body budget.
+	overbudget bool // Whether to stop buffering since the budget is exceeded.
+
+	eventstream bool // Whether the body is the infinite stream of the server-sent events.
+
+	path         string // The request path to find the forced content type.
+	ndjsonlines  int    // The number of the lines left to buffer for the streaming JSON body.
+	ndjsonmaxlen int    // The maximum length of the buffered streaming JSON body.
+	ndjson       bool   // Whether the body is the streaming JSON.
+
+	streammaxlen int  // The maximum length to buffer the body without Content-Length.
+	streamed     bool // Whether only the head of the streaming body is buffered.
+
+	logbody bool // Buffer the response body for any status.
+	errbody bool // Buffer the response body for the error status.
+	errlen  int  // The maximum length of the error response body to log.
 }
 
-func newResponseWriter(w http.ResponseWriter, buf *bytes.Buffer) *responseWriter {
-	return &responseWriter{ResponseWriter: w, buf: buf}
+func newResponseWriter(w http.ResponseWriter, logbody, errbody bool, errlen int) *responseWriter {
+	return &responseWriter{ResponseWriter: w, logbody: logbody, errbody: errbody, errlen: errlen}
 }
 
+// Unwrap returns the original response writer, which is used by
+// http.ResponseController to find the methods not implemented by the wrapper,
+// such as SetReadDeadline, SetWriteDeadline and EnableFullDuplex.
 func (r *responseWriter) Unwrap() http.ResponseWriter { return r.ResponseWriter }
 
+// setstatus records the status code and decides whether to buffer the body.
+//
+// It must be called with the lock held.
+func (r *responseWriter) setstatus(code int) {
+	if r.status > 0 || (code < 200 && code != http.StatusSwitchingProtocols) {
+		return
+	}
+
+	r.status = code
+	if r.released {
+		return
+	}
+
+	switch {
+	case r.logbody && iseventstream(r.ResponseWriter.Header()):
+		// Never buffer or spill the infinite stream of the server-sent events.
+		r.eventstream, r.spill = true, nil
+
+	case r.logbody:
+		r.buf = getbuffer()
+		metrics.respcaptured.Add(1)
+		if r.spill = r.spill.forct(getpathct(resppathcts, r.path, r.ResponseWriter.Header())); r.spill != nil {
+			// Only keep the head in memory, and spill the whole body.
+			r.limit = r.spill.bodymaxlen + 1
+		}
+
+		if r.streammaxlen > 0 && r.ResponseWriter.Header().Get("Content-Length") == "" {
+			// Only buffer the head of the long-running stream, such as chunked.
+			if r.limit == 0 || r.limit > r.streammaxlen {
+				r.limit = r.streammaxlen
+			}
+			r.streamed = true
+		}
+
+		if r.ndjsonlines > 0 {
+			if isndjsonct(getpathct(resppathcts, r.path, r.ResponseWriter.Header())) {
+				// Only buffer the first lines of the stream instead of spilling it.
+				r.limit, r.spill, r.ndjson = r.ndjsonmaxlen, nil, true
+			}
+		}
+
+	case r.errbody && code >= 400:
+		r.buf = getbuffer()
+		r.limit = r.errlen + 1
+		metrics.respcaptured.Add(1)
+	}
+}
+
+// snapshot returns the bytes of the buffered body, which are not changed
+// by the later writes and are valid until the buffer is released,
+// and the total length of the written body.
+//
+// If the body is not buffered, ok is false.
+func (r *responseWriter) snapshot() (data []byte, size int, ok bool) {
+	r.lock.Lock()
+	if r.buf != nil && !r.overbudget {
+		data = r.buf.Bytes()
+	}
+	size, ok = r.size, !r.released && (r.logbody || r.buf != nil)
+	r.lock.Unlock()
+	return
+}
+
+// getstatus returns the status code of the response.
+func (r *responseWriter) getstatus() (status int) {
+	r.lock.Lock()
+	status = r.status
+	r.lock.Unlock()
+	return
+}
+
+// getwritten returns the status code of the response, which is 200
+// if not written, and the number of the bytes written into the body.
+func (r *responseWriter) getwritten() (status, size int) {
+	r.lock.Lock()
+	status, size = r.status, r.size
+	r.lock.Unlock()
+
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return
+}
+
+// detach detaches the buffer from the writer and returns it.
+func (r *responseWriter) detach() (buf *bytes.Buffer) {
+	r.lock.Lock()
+	buf, r.buf, r.released = r.buf, nil, true
+	if r.reserved > 0 {
+		bodybudgetused.Add(-int64(r.reserved))
+		r.reserved = 0
+	}
+	if r.decoded != nil {
+		putbuffer(r.decoded)
+		r.decoded = nil
+	}
+	r.spill.close()
+	r.lock.Unlock()
+	return
+}
+
+// gettail returns the last bytes of the written body with the length size.
+func (r *responseWriter) gettail(size int) []byte {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.released {
+		return nil
+	}
+	return r.tail.last(size)
+}
+
+// isstreamed reports whether only the head of the streaming body is buffered.
+func (r *responseWriter) isstreamed() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.streamed
+}
+
+// skipreason returns the reason why the body is not buffered wholly,
+// such as "memory_budget" and "event_stream", or "" if not skipped.
+func (r *responseWriter) skipreason() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	switch {
+	case r.overbudget:
+		return "memory_budget"
+	case r.eventstream:
+		return "event_stream"
+	default:
+		return ""
+	}
+}
+
+// appendspillattrs appends the attributes of the spilled body if spilled.
+func (r *responseWriter) appendspillattrs(appendAttr func(...slog.Attr)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.released {
+		r.spill.appendattrs(appendAttr, "respbody", true)
+	}
+}
+
+// sum returns the hex-encoded SHA-256 hash of the whole written body.
+func (r *responseWriter) sum() (sum string, ok bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.hash == nil || r.released {
+		return
+	}
+	return hex.EncodeToString(r.hash.Sum(nil)), true
+}
+
+// decode decompresses the whole buffered body data with the length size
+// only once, and returns the decompressed body and its length.
+//
+// If the body is not buffered wholly or fails to be decompressed, ok is false.
+func (r *responseWriter) decode(data []byte, size int, encoding string, maxlen int) (ddata []byte, dsize int, ok bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.decoded == nil {
+		if r.released || len(data) == 0 || len(data) != size {
+			return
+		}
+
+		var limit int
+		if maxlen > 0 {
+			limit = maxlen + 1
+		}
+
+		buf := getbuffer()
+		n, err := decodebody(buf, data, encoding, limit)
+		if err != nil {
+			putbuffer(buf)
+			return
+		}
+		r.decoded, r.dsize = buf, n
+	}
+	return r.decoded.Bytes(), r.dsize, true
+}
+
+func (r *responseWriter) WriteHeader(code int) {
+	r.lock.Lock()
+	r.setstatus(code)
+	r.lock.Unlock()
+	r.ResponseWriter.WriteHeader(code)
+}
+
 func (r *responseWriter) Write(p []byte) (n int, err error) {
+	r.lock.Lock()
+	r.setstatus(http.StatusOK)
+	r.lock.Unlock()
+
 	if n, err = r.ResponseWriter.Write(p); n > 0 {
-		r.buf.Write(p[:n])
+		r.lock.Lock()
+		r.write(p[:n])
+		r.lock.Unlock()
 	}
 	return
 }
 
 func (r *responseWriter) WriteString(s string) (n int, err error) {
+	r.lock.Lock()
+	r.setstatus(http.StatusOK)
+	r.lock.Unlock()
+
 	if n, err = io.WriteString(r.ResponseWriter, s); n > 0 {
-		r.buf.WriteString(s[:n])
+		r.lock.Lock()
+		r.write(unsafe.Slice(unsafe.StringData(s), n))
+		r.lock.Unlock()
 	}
 	return
 }
+
+// ReadFrom implements the interface io.ReaderFrom, which uses ReadFrom
+// of the original response writer, such as sendfile, if the body is not
+// buffered, or copies the data by Write to buffer it.
+func (r *responseWriter) ReadFrom(src io.Reader) (n int64, err error) {
+	r.lock.Lock()
+	r.setstatus(http.StatusOK)
+	buffered := r.buf != nil || r.hash != nil
+	r.lock.Unlock()
+
+	if rf, ok := r.ResponseWriter.(io.ReaderFrom); ok && !buffered {
+		n, err = rf.ReadFrom(src)
+		r.lock.Lock()
+		r.size += int(n)
+		r.lock.Unlock()
+		return
+	}
+
+	return io.Copy(writerOnly{r}, src)
+}
+
+// writerOnly hides the method ReadFrom to avoid io.Copy calling it recursively.
+type writerOnly struct{ io.Writer }
+
+// Flush implements the interface http.Flusher, which is used by SSE.
+func (r *responseWriter) Flush() {
+	r.lock.Lock()
+	r.setstatus(http.StatusOK)
+	r.lock.Unlock()
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Hijack implements the interface http.Hijacker, which is used by WebSocket.
+//
+// If the original response writer does not support it,
+// return an error wrapping http.ErrNotSupported.
+func (r *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(r.ResponseWriter).Hijack()
+}
+
+// Push implements the interface http.Pusher, which is used by HTTP/2 server push.
+//
+// If the original response writer does not support it,
+// return http.ErrNotSupported.
+func (r *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := r.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// write copies p into the buffer within the limit.
+//
+// It must be called with the lock held.
+func (r *responseWriter) write(p []byte) {
+	r.size += len(p)
+	r.tail.write(p)
+	if r.hash != nil {
+		r.hash.Write(p)
+	}
+	if r.buf == nil {
+		return
+	}
+
+	if r.spill != nil && (r.spill.file != nil || r.buf.Len()+len(p) > r.limit) {
+		r.spill.write(r.buf.Bytes(), p)
+	}
+
+	if r.ndjson {
+		if r.ndjsonlines == 0 {
+			return // All the lines to log have been buffered.
+		}
+		p = r.cutlines(p)
+	}
+
+	if r.limit > 0 {
+		if left := r.limit - r.buf.Len(); left <= 0 {
+			return
+		} else if len(p) > left {
+			p = p[:left]
+		}
+	}
+
+	if r.budget > 0 {
+		if r.overbudget {
+			return
+		} else if !reservebudget(len(p), r.budget) {
+			// Stop buffering, and the buffered data is not logged.
+			r.overbudget = true
+			metrics.skippedbudget.Add(1)
+			return
+		}
+		r.reserved += len(p)
+	}
+	r.buf.Write(p)
+}
@@ -0,0 +1,251 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/xgfone/gconf/v6"
+)
+
+// DefaultConfig returns the default configuration,
+// which is the same as the default values of the options.
+func DefaultConfig() Config {
+	return Config{
+		LogQuery:       optdefault[bool](logQuery),
+		LogRequestLine: optdefault[bool](logRequestLine),
+		LogReqBody:     optdefault[bool](logReqBody),
+		LogRespBody:    optdefault[bool](logRespBody),
+		LogReqHeaders:  optdefault[bool](logReqHeaders),
+		LogRespHeaders: optdefault[bool](logRespHeaders),
+
+		RedactQueries: optdefault[[]string](logRedactQueries),
+		RedactHeaders: optdefault[[]string](logRedactHeaders),
+		RedactFields:  optdefault[[]string](logRedactFields),
+		RedactXML:     optdefault[[]string](logRedactXML),
+
+		AuthMask:      optdefault[string](logAuthMask),
+		AuthPrefixLen: optdefault[int](logAuthPrefixLen),
+
+		LogCookies:    optdefault[bool](logCookies),
+		RedactCookies: optdefault[[]string](logRedactCookies),
+		ScrubPII:      optdefault[[]string](logScrubPII),
+
+		LogClientIP:     optdefault[bool](logClientIP),
+		ClientIPHeaders: optdefault[[]string](logClientIPHeaders),
+		TrustedProxies:  optdefault[[]string](logTrustedProxies),
+
+		GraphQLPaths: optdefault[[]string](logGraphQLPaths),
+
+		LogBasicUser: optdefault[bool](logBasicUser),
+		JWTClaims:    optdefault[[]string](logJWTClaims),
+
+		LogTLS:              optdefault[bool](logTLS),
+		LogClientCert:       optdefault[bool](logClientCert),
+		LogClientCertDetail: optdefault[bool](logClientCertDetail),
+
+		LogReferer:         optdefault[bool](logReferer),
+		LogRefererPathOnly: optdefault[bool](logRefererPathOnly),
+
+		LogTrailers: optdefault[bool](logTrailers),
+		LogStatus:   optdefault[bool](logStatus),
+		LogCurl:     optdefault[bool](logCurl),
+
+		MutatingBodyOnly: optdefault[bool](logMutatingBodyOnly),
+
+		BodyMaxLen:   optdefault[int](logBodyMaxLen),
+		BodyMaxLens:  optdefault[[]string](logBodyMaxLens),
+		BodyBudget:   optdefault[int](logBodyBudget),
+		BufSize:      optdefault[int](logBufSize),
+		BufMaxCap:    optdefault[int](logBufMaxCap),
+		ZeroCopy:     optdefault[bool](logZeroCopy),
+		TruncateBody: optdefault[bool](logTruncateBody),
+		TruncateTail: optdefault[int](logTruncateTail),
+		LazyReqBody:  optdefault[bool](logLazyReqBody),
+		BodyOnError:  optdefault[bool](logBodyOnError),
+		BodyTypes:    optdefault[[]string](logBodyTypes),
+		BodyFields:   optdefault[[]string](logBodyFields),
+		CTBodyFields: optdefault[[]string](logCTBodyFields),
+		LogXML:       optdefault[bool](logXML),
+		XMLCompact:   optdefault[bool](logXMLCompact),
+		JSONCompact:  optdefault[bool](logJSONCompact),
+		PrettyBody:   optdefault[bool](logPrettyBody),
+		FormBody:     optdefault[bool](logFormBody),
+		NDJSONLines:  optdefault[int](logNDJSONLines),
+		StreamMaxLen: optdefault[int](logStreamMaxLen),
+
+		BodySampleRate: optdefault[float64](logBodySampleRate),
+		SlowThreshold:  optdefault[time.Duration](logSlowThreshold),
+		MultipartMeta:  optdefault[bool](logMultipartMeta),
+		BodyHash:       optdefault[bool](logBodyHash),
+		BodyHashOnly:   optdefault[bool](logBodyHashOnly),
+
+		BinaryTypes:      optdefault[[]string](logBinaryTypes),
+		BinarySnippetLen: optdefault[int](logBinarySnippetLen),
+		BinaryEncoding:   optdefault[string](logBinaryEncoding),
+
+		IncludeTextTypes: optdefault[bool](logIncludeTextTypes),
+		BinaryThreshold:  optdefault[float64](logBinaryThreshold),
+		EmptyBody:        optdefault[string](logEmptyBody),
+		StrictOrdering:   optdefault[bool](logStrictOrdering),
+		PushHeader:       optdefault[string](logPushHeader),
+		DefaultService:   optdefault[string](logDefaultService),
+
+		AttrOrder:   optdefault[[]string](logAttrOrder),
+		GroupAttrs:  optdefault[bool](logGroupAttrs),
+		EventBuffer: optdefault[int](logEventBuffer),
+		SizeWindow:  optdefault[int](logSizeWindow),
+
+		FlightRecorder: optdefault[int](logFlightRecorder),
+
+		SpillDir:       optdefault[string](logSpillDir),
+		SpillMaxLen:    optdefault[int](logSpillMaxLen),
+		SpillRetention: optdefault[time.Duration](logSpillRetention),
+
+		CaptureDir:        optdefault[string](logCaptureDir),
+		CaptureSampleRate: optdefault[float64](logCaptureSampleRate),
+		CaptureMaxFiles:   optdefault[int](logCaptureMaxFiles),
+
+		LogErrorBodies:  optdefault[bool](logErrorBodies),
+		ErrorBodyMaxLen: optdefault[int](logErrorBodyMaxLen),
+
+		SkipHeaders:  optdefault[[]string](logSkipHeaders),
+		ForceHeaders: optdefault[[]string](logForceHeaders),
+		Statuses:     optdefault[[]string](logStatuses),
+
+		DebugHeader: optdefault[string](logDebugHeader),
+		DebugToken:  optdefault[string](logDebugToken),
+	}.clone()
+}
+
+func optdefault[T any](opt interface{ Opt() gconf.Opt }) T {
+	v, _ := opt.Opt().Default.(T)
+	return v
+}
+
+// Option is used to configure the Logger.
+type Option func(*Config)
+
+// WithConfig returns an option to update the configuration by f,
+// which is used to set the fields not covered by the other options.
+func WithConfig(f func(c *Config)) Option { return f }
+
+// WithLogQuery returns an option to set whether to log the request query.
+func WithLogQuery(log bool) Option {
+	return func(c *Config) { c.LogQuery = log }
+}
+
+// WithLogReqBody returns an option to set whether to log the request body.
+func WithLogReqBody(log bool) Option {
+	return func(c *Config) { c.LogReqBody = log }
+}
+
+// WithLogRespBody returns an option to set whether to log the response body.
+func WithLogRespBody(log bool) Option {
+	return func(c *Config) { c.LogRespBody = log }
+}
+
+// WithLogReqHeaders returns an option to set whether to log the request headers.
+func WithLogReqHeaders(log bool) Option {
+	return func(c *Config) { c.LogReqHeaders = log }
+}
+
+// WithLogRespHeaders returns an option to set whether to log the response headers.
+func WithLogRespHeaders(log bool) Option {
+	return func(c *Config) { c.LogRespHeaders = log }
+}
+
+// WithBodyMaxLen returns an option to set the maximum length of the body to log.
+func WithBodyMaxLen(maxlen int) Option {
+	return func(c *Config) { c.BodyMaxLen = maxlen }
+}
+
+// WithBodyTypes returns an option to set the content types of the body to log.
+func WithBodyTypes(cts ...string) Option {
+	return func(c *Config) { c.BodyTypes = slices.Clone(cts) }
+}
+
+// WithRedactHeaders returns an option to set the names of the headers to redact.
+func WithRedactHeaders(names ...string) Option {
+	return func(c *Config) { c.RedactHeaders = slices.Clone(names) }
+}
+
+// WithIgnorePaths returns an option to set the ignored paths, which are not logged.
+//
+// If the path ends with "/", it is a prefix matching; Or, an equal matching.
+func WithIgnorePaths(paths ...string) Option {
+	return func(c *Config) { c.IgnorePaths = slices.Clone(paths) }
+}
+
+// WithKeys returns an option to rename the keys of the attributes.
+func WithKeys(keys Keys) Option {
+	return func(c *Config) { c.Keys = keys }
+}
+
+// Logger is the request logger with its own configuration,
+// which does not depend on the global options.
+//
+// The other package-level settings, such as SetErrorGetter,
+// SetServiceMapping and ForceBodyContentType, are still shared.
+// And the options eventbuffer and sizewindow are only global.
+type Logger struct {
+	config Config
+}
+
+// New returns a new Logger, whose configuration starts from DefaultConfig.
+func New(opts ...Option) *Logger {
+	c := DefaultConfig()
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &Logger{config: c}
+}
+
+// Config returns a copy of the configuration of the logger.
+func (l *Logger) Config() Config { return l.config.clone() }
+
+// WithContext returns a new context with the configuration of the logger,
+// so that Enabled, WrapReqRespBody and Collect use it instead of the
+// global options for the request with the context.
+//
+// It is also used by NewLoggingTransport for the outbound request.
+func (l *Logger) WithContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, configkey, &l.config)
+}
+
+// WrapHandler is the same as the package-level WrapHandler,
+// but uses the configuration of the logger.
+func (l *Logger) WrapHandler(next http.Handler) http.Handler {
+	next = WrapHandler(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(l.WithContext(r.Context())))
+	})
+}
+
+// Middleware is the same as the package-level Middleware,
+// but uses the configuration of the logger.
+func (l *Logger) Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	middleware := Middleware(logger)
+	return func(next http.Handler) http.Handler {
+		next = middleware(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(l.WithContext(r.Context())))
+		})
+	}
+}
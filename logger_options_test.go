@@ -0,0 +1,103 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	c := DefaultConfig()
+	if c.BodyMaxLen != 2048 {
+		t.Errorf("expect BodyMaxLen %d, but got %d", 2048, c.BodyMaxLen)
+	}
+	if c.EmptyBody != "omit" {
+		t.Errorf("expect EmptyBody '%s', but got '%s'", "omit", c.EmptyBody)
+	}
+	if !slices.Contains(c.BodyTypes, "application/json") {
+		t.Errorf("expect BodyTypes to contain '%s', but got %v", "application/json", c.BodyTypes)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	logger := New(
+		WithLogReqBody(true),
+		WithLogRespBody(true),
+		WithBodyMaxLen(16),
+		WithBodyTypes("text/plain"),
+		WithIgnorePaths("/health"),
+	)
+
+	if c := logger.Config(); !c.LogReqBody || c.BodyMaxLen != 16 {
+		t.Errorf("unexpected config: %+v", c)
+	}
+	if logReqBody.Get() || logBodyMaxLen.Get() != 2048 {
+		t.Error("the global options are modified")
+	}
+
+	handler := new(recordHandler)
+	middleware := logger.Middleware(slog.New(handler))
+	server := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write(body)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("hello"))
+	req.Header.Set("Content-Type", "text/plain")
+	server.ServeHTTP(httptest.NewRecorder(), req)
+
+	attrs := handler.last()
+	if v := attrs["reqbody"].String(); v != "hello" {
+		t.Errorf("expect reqbody '%s', but got '%s'", "hello", v)
+	}
+	if v := attrs["respbody"].String(); v != "hello" {
+		t.Errorf("expect respbody '%s', but got '%s'", "hello", v)
+	}
+
+	// The body of the content type not configured by the logger is not logged.
+	req = httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	server.ServeHTTP(httptest.NewRecorder(), req)
+	if _, ok := handler.last()["reqbody"]; ok {
+		t.Error("unexpect attr reqbody")
+	}
+
+	// The path ignored by the logger is not logged.
+	handler = new(recordHandler)
+	server = logger.Middleware(slog.New(handler))(http.NotFoundHandler())
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	if attrs := handler.last(); attrs != nil {
+		t.Errorf("unexpect the log record: %v", attrs)
+	}
+
+	// The global options are still used without the logger.
+	handler = new(recordHandler)
+	req = httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("hello"))
+	req.Header.Set("Content-Type", "text/plain")
+	Middleware(slog.New(handler))(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), req)
+	if _, ok := handler.last()["reqbody"]; ok {
+		t.Error("unexpect attr reqbody")
+	}
+}
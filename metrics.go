@@ -0,0 +1,130 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"expvar"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	expvar.Publish("loggerext", expvar.Func(func() any { return Metrics() }))
+}
+
+// LatencyBucket is the cumulative bucket of the latency histogram,
+// which counts the observations less than or equal to LE.
+type LatencyBucket struct {
+	LE    string `json:"le"` // Such as "100µs" or "+Inf".
+	Count int64  `json:"count"`
+}
+
+// OverheadMetrics is the metrics of the overhead of the body logging.
+type OverheadMetrics struct {
+	ReqBodiesCaptured  int64 `json:"reqbodiescaptured"`
+	RespBodiesCaptured int64 `json:"respbodiescaptured"`
+	BytesBuffered      int64 `json:"bytesbuffered"`
+
+	BodiesSkippedBySize int64 `json:"bodiesskippedbysize"`
+	BodiesSkippedByType int64 `json:"bodiesskippedbytype"`
+
+	BodiesSkippedByBudget int64 `json:"bodiesskippedbybudget"`
+	BodyBudgetInUse       int64 `json:"bodybudgetinuse"` // The bytes reserved from bodybudget.
+
+	BufferPoolHits   int64 `json:"bufferpoolhits"`
+	BufferPoolMisses int64 `json:"bufferpoolmisses"`
+
+	CollectCount    int64           `json:"collectcount"`
+	CollectDuration time.Duration   `json:"collectduration"` // The total duration of Collect.
+	CollectLatency  []LatencyBucket `json:"collectlatency"`
+}
+
+var latencybounds = [...]time.Duration{
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+}
+
+var metrics struct {
+	reqcaptured   atomic.Int64
+	respcaptured  atomic.Int64
+	buffered      atomic.Int64
+	skippedsize   atomic.Int64
+	skippedtype   atomic.Int64
+	skippedbudget atomic.Int64
+	poolgets      atomic.Int64
+	poolmisses    atomic.Int64
+
+	collects  atomic.Int64
+	collectns atomic.Int64
+	latencies [len(latencybounds) + 1]atomic.Int64 // The last is +Inf.
+}
+
+// Metrics returns the snapshot of the metrics of the overhead
+// of the body logging, which is also published by expvar as "loggerext".
+func Metrics() OverheadMetrics {
+	gets, misses := metrics.poolgets.Load(), metrics.poolmisses.Load()
+	m := OverheadMetrics{
+		ReqBodiesCaptured:  metrics.reqcaptured.Load(),
+		RespBodiesCaptured: metrics.respcaptured.Load(),
+		BytesBuffered:      metrics.buffered.Load(),
+
+		BodiesSkippedBySize: metrics.skippedsize.Load(),
+		BodiesSkippedByType: metrics.skippedtype.Load(),
+
+		BodiesSkippedByBudget: metrics.skippedbudget.Load(),
+		BodyBudgetInUse:       bodybudgetused.Load(),
+
+		BufferPoolHits:   max(gets-misses, 0),
+		BufferPoolMisses: misses,
+
+		CollectCount:    metrics.collects.Load(),
+		CollectDuration: time.Duration(metrics.collectns.Load()),
+		CollectLatency:  make([]LatencyBucket, len(metrics.latencies)),
+	}
+
+	var count int64
+	for i := range metrics.latencies {
+		count += metrics.latencies[i].Load()
+		m.CollectLatency[i].Count = count
+		if i < len(latencybounds) {
+			m.CollectLatency[i].LE = latencybounds[i].String()
+		} else {
+			m.CollectLatency[i].LE = "+Inf"
+		}
+	}
+
+	return m
+}
+
+// observecollect records the latency of Collect.
+func observecollect(start time.Time) {
+	latency := time.Since(start)
+	metrics.collects.Add(1)
+	metrics.collectns.Add(int64(latency))
+
+	index := len(latencybounds)
+	for i, bound := range latencybounds {
+		if latency <= bound {
+			index = i
+			break
+		}
+	}
+	metrics.latencies[index].Add(1)
+}
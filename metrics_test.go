@@ -0,0 +1,73 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logRespBody.Set(true)
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logRespBody.Set(false)
+	}()
+
+	before := Metrics()
+
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("abc"))
+	req.Header.Set("Content-Type", "text/plain")
+	serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("xyz"))
+	}, req)
+
+	req = httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("abc"))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+
+	after := Metrics()
+	if n := after.ReqBodiesCaptured - before.ReqBodiesCaptured; n != 1 {
+		t.Errorf("expect %d captured request bodies, but got %d", 1, n)
+	}
+	if n := after.RespBodiesCaptured - before.RespBodiesCaptured; n != 1 {
+		t.Errorf("expect %d captured response bodies, but got %d", 1, n)
+	}
+	if n := after.BodiesSkippedByType - before.BodiesSkippedByType; n != 2 {
+		t.Errorf("expect %d bodies skipped by type, but got %d", 2, n)
+	}
+	if n := after.BytesBuffered - before.BytesBuffered; n != 6 {
+		t.Errorf("expect %d buffered bytes, but got %d", 6, n)
+	}
+	if n := after.CollectCount - before.CollectCount; n != 2 {
+		t.Errorf("expect %d collects, but got %d", 2, n)
+	}
+	if last := after.CollectLatency[len(after.CollectLatency)-1]; last.LE != "+Inf" || last.Count != after.CollectCount {
+		t.Errorf("unexpected the last latency bucket %+v", last)
+	}
+
+	var m OverheadMetrics
+	if err := json.Unmarshal([]byte(expvar.Get("loggerext").String()), &m); err != nil {
+		t.Fatal(err)
+	} else if m.CollectCount < after.CollectCount {
+		t.Errorf("unexpected expvar metrics %+v", m)
+	}
+}
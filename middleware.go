@@ -0,0 +1,160 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Middleware returns a framework-agnostic http middleware, which wraps
+// the request and response like WrapHandler, and emits an access log record
+// containing the attributes collected by Collect after handling the request.
+//
+// So it can be used with the routers supporting the signature
+// func(http.Handler) http.Handler, such as chi. For gin and echo,
+// see the sub-packages ginext and echoext.
+//
+// If logger is nil, use slog.Default() instead.
+// If the option statuses is set, the request whose response status code
+// does not match is not logged. If the option status is enabled,
+// the attribute status is appended by Collect instead.
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !Enabled(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			w, r = WrapReqRespBody(w, r)
+			defer Release(w, r)
+
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+
+			c := getconfig(r)
+			if !c.matchstatus(sw) {
+				return
+			}
+
+			attrs := make([]slog.Attr, 0, 16)
+			attrs = append(attrs,
+				slog.String("raddr", r.RemoteAddr),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+			)
+			if !c.LogStatus {
+				attrs = append(attrs, slog.Int("status", sw.getstatus()))
+			}
+			attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+
+			Collect(sw, r, func(as ...slog.Attr) { attrs = append(attrs, as...) })
+			logger.LogAttrs(r.Context(), slog.LevelInfo, "http request", attrs...)
+		})
+	}
+}
+
+// statusWriter records the status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status   int
+	hijacked bool
+}
+
+// Unwrap returns the original response writer for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *statusWriter) getstatus() int {
+	switch {
+	case w.status > 0:
+		return w.status
+	case w.hijacked:
+		// The response is written by the handler on the hijacked connection,
+		// such as the handshake of WebSocket.
+		return http.StatusSwitchingProtocols
+	default:
+		return http.StatusOK
+	}
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 && code >= 200 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *statusWriter) WriteString(s string) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return io.WriteString(w.ResponseWriter, s)
+}
+
+// Flush implements the interface http.Flusher, which is used by SSE.
+func (w *statusWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// ReadFrom implements the interface io.ReaderFrom, which is used by io.Copy.
+func (w *statusWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+	return io.Copy(writerOnly{w.ResponseWriter}, src)
+}
+
+// Push implements the interface http.Pusher, which is used by HTTP/2 server push.
+//
+// If the original response writer does not support it,
+// return http.ErrNotSupported.
+func (w *statusWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Hijack implements the interface http.Hijacker, which is used by WebSocket.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
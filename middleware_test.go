@@ -0,0 +1,205 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareWithServeMux(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logRespBody.Set(true)
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logRespBody.Set(false)
+	}()
+
+	handler := new(recordHandler)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users/123", strings.NewReader(`{"id":123}`))
+	req.Header.Set("Content-Type", "application/json")
+	Middleware(slog.New(handler))(mux).ServeHTTP(httptest.NewRecorder(), req)
+
+	attrs := handler.last()
+	if attrs == nil {
+		t.Fatal("expect a log record, but got nil")
+	}
+
+	if v := attrs["status"].Int64(); v != http.StatusCreated {
+		t.Errorf("expect status %d, but got %d", http.StatusCreated, v)
+	}
+	if v := attrs["path"].String(); v != "/users/123" {
+		t.Errorf("expect path '%s', but got '%s'", "/users/123", v)
+	}
+	if v := attrs["reqbodylen"].Int64(); v != 10 {
+		t.Errorf("expect reqbodylen %d, but got %d", 10, v)
+	}
+	if v := attrs["respbodylen"].Int64(); v != 10 {
+		t.Errorf("expect respbodylen %d, but got %d", 10, v)
+	}
+	if _, ok := attrs["respbody"]; !ok {
+		t.Error("expect respbody, but got not")
+	}
+}
+
+func TestMiddlewareResponseController(t *testing.T) {
+	_ = logRespBody.Set(true)
+	defer func() { _ = logRespBody.Set(false) }()
+
+	errs := make(chan error, 4)
+	handler := new(recordHandler)
+	server := httptest.NewServer(Middleware(slog.New(handler))(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			rc := http.NewResponseController(w)
+			errs <- rc.SetReadDeadline(time.Now().Add(time.Second))
+			errs <- rc.SetWriteDeadline(time.Now().Add(time.Second))
+			errs <- rc.EnableFullDuplex()
+
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = io.WriteString(w, "hello")
+			errs <- rc.Flush()
+		})))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if string(body) != "hello" {
+		t.Errorf("expect the response body '%s', but got '%s'", "hello", body)
+	}
+	if v := handler.last()["respbody"].String(); v != "hello" {
+		t.Errorf("expect respbody '%s', but got '%s'", "hello", v)
+	}
+}
+
+func TestMiddlewareInterfaces(t *testing.T) {
+	_ = logRespBody.Set(true)
+	defer func() { _ = logRespBody.Set(false) }()
+
+	handler := new(recordHandler)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/path", nil)
+	Middleware(slog.New(handler))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("expect the response writer to implement http.Flusher")
+		}
+		if _, ok := w.(http.Hijacker); !ok {
+			t.Error("expect the response writer to implement http.Hijacker")
+		}
+
+		if pusher, ok := w.(http.Pusher); !ok {
+			t.Error("expect the response writer to implement http.Pusher")
+		} else if err := pusher.Push("/style.css", nil); err != http.ErrNotSupported {
+			t.Errorf("expect error ErrNotSupported, but got %v", err)
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		if rf, ok := w.(io.ReaderFrom); !ok {
+			t.Error("expect the response writer to implement io.ReaderFrom")
+		} else {
+			_, _ = rf.ReadFrom(strings.NewReader("hello"))
+		}
+	})).ServeHTTP(rec, req)
+
+	if v := rec.Body.String(); v != "hello" {
+		t.Errorf("expect the response body '%s', but got '%s'", "hello", v)
+	}
+	if v := handler.last()["respbody"].String(); v != "hello" {
+		t.Errorf("expect respbody '%s', but got '%s'", "hello", v)
+	}
+}
+
+func TestMiddlewareStatuses(t *testing.T) {
+	_ = logStatuses.Set([]string{"4xx", "500"})
+	defer func() { _ = logStatuses.Set([]string{}) }()
+
+	handler := new(recordHandler)
+	middleware := Middleware(slog.New(handler))
+	for _, status := range []int{200, 204, 404, 499, 500, 503} {
+		req := httptest.NewRequest(http.MethodGet, "/path", nil)
+		middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		})).ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(handler.records) != 3 {
+		t.Fatalf("expect %d records, but got %d", 3, len(handler.records))
+	}
+	for i, status := range []int64{404, 499, 500} {
+		if v := handler.records[i]["status"].Int64(); v != status {
+			t.Errorf("expect status %d, but got %d", status, v)
+		}
+	}
+
+	_ = logQuery.Set(true)
+	defer func() { _ = logQuery.Set(false) }()
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, httptest.NewRequest(http.MethodGet, "/path?a=1", nil))
+	if len(attrs) != 0 {
+		t.Errorf("expect no attributes for the status 200, but got %v", attrs)
+	}
+
+	c := EffectiveConfig()
+	c.Statuses = []string{"6xx", "4x"}
+	if err := c.Validate(); err == nil {
+		t.Error("expect an error for the invalid status patterns")
+	}
+}
+
+func TestMiddlewareRoute(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {})
+	SetRouteGetter(func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		return pattern
+	})
+	defer SetRouteGetter(nil)
+
+	handler := new(recordHandler)
+	Middleware(slog.New(handler))(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/123", nil))
+
+	if v := handler.last()["route"].String(); v != "/users/" {
+		t.Errorf("expect route '%s', but got '%s'", "/users/", v)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/users/123", nil)
+	req = req.WithContext(WithRoute(req.Context(), "/users/:id"))
+	if v := collectAttrs(httptest.NewRecorder(), req)["route"].String(); v != "/users/:id" {
+		t.Errorf("expect route '%s', but got '%s'", "/users/:id", v)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// decodemsgpack transcodes the MessagePack body into JSON.
+func decodemsgpack(_ *http.Request, _ string, data []byte) ([]byte, error) {
+	return transcodebin(data, msgpackvalue)
+}
+
+func msgpackvalue(r *binreader, buf *bytes.Buffer, depth int) (err error) {
+	if depth > maxbindepth {
+		return errbindepth
+	}
+
+	p, err := r.next(1)
+	if err != nil {
+		return
+	}
+
+	switch b := p[0]; {
+	case b <= 0x7f: // positive fixint
+		buf.WriteString(strconv.FormatUint(uint64(b), 10))
+	case b >= 0xe0: // negative fixint
+		buf.WriteString(strconv.FormatInt(int64(int8(b)), 10))
+	case b <= 0x8f: // fixmap
+		err = msgpackmap(r, buf, uint64(b&0x0f), depth)
+	case b <= 0x9f: // fixarray
+		err = msgpackarray(r, buf, uint64(b&0x0f), depth)
+	case b <= 0xbf: // fixstr
+		err = msgpackstr(r, buf, uint64(b&0x1f))
+
+	case b == 0xc0:
+		buf.WriteString("null")
+	case b == 0xc2:
+		buf.WriteString("false")
+	case b == 0xc3:
+		buf.WriteString("true")
+
+	case b >= 0xc4 && b <= 0xc6: // bin 8, 16, 32
+		var n uint64
+		if n, err = r.uint(1 << (b - 0xc4)); err == nil {
+			if p, err = r.next(n); err == nil {
+				writejsonvalue(buf, p)
+			}
+		}
+
+	case b >= 0xc7 && b <= 0xc9: // ext 8, 16, 32
+		var n uint64
+		if n, err = r.uint(1 << (b - 0xc7)); err == nil {
+			err = msgpackext(r, buf, n)
+		}
+
+	case b == 0xca: // float 32
+		var v uint64
+		if v, err = r.uint(4); err == nil {
+			writejsonfloat(buf, float64(math.Float32frombits(uint32(v))), 32)
+		}
+
+	case b == 0xcb: // float 64
+		var v uint64
+		if v, err = r.uint(8); err == nil {
+			writejsonfloat(buf, math.Float64frombits(v), 64)
+		}
+
+	case b >= 0xcc && b <= 0xcf: // uint 8, 16, 32, 64
+		var v uint64
+		if v, err = r.uint(1 << (b - 0xcc)); err == nil {
+			buf.WriteString(strconv.FormatUint(v, 10))
+		}
+
+	case b >= 0xd0 && b <= 0xd3: // int 8, 16, 32, 64
+		var v uint64
+		size := 1 << (b - 0xd0)
+		if v, err = r.uint(size); err == nil {
+			shift := 64 - 8*size
+			buf.WriteString(strconv.FormatInt(int64(v<<shift)>>shift, 10))
+		}
+
+	case b >= 0xd4 && b <= 0xd8: // fixext 1, 2, 4, 8, 16
+		err = msgpackext(r, buf, 1<<(b-0xd4))
+
+	case b >= 0xd9 && b <= 0xdb: // str 8, 16, 32
+		var n uint64
+		if n, err = r.uint(1 << (b - 0xd9)); err == nil {
+			err = msgpackstr(r, buf, n)
+		}
+
+	case b == 0xdc || b == 0xdd: // array 16, 32
+		var n uint64
+		if n, err = r.uint(2 << (b - 0xdc)); err == nil {
+			err = msgpackarray(r, buf, n, depth)
+		}
+
+	case b == 0xde || b == 0xdf: // map 16, 32
+		var n uint64
+		if n, err = r.uint(2 << (b - 0xde)); err == nil {
+			err = msgpackmap(r, buf, n, depth)
+		}
+
+	default:
+		err = fmt.Errorf("invalid msgpack type 0x%x", b)
+	}
+
+	return
+}
+
+func msgpackstr(r *binreader, buf *bytes.Buffer, n uint64) error {
+	p, err := r.next(n)
+	if err == nil {
+		writejsonvalue(buf, string(p))
+	}
+	return err
+}
+
+func msgpackarray(r *binreader, buf *bytes.Buffer, n uint64, depth int) error {
+	buf.WriteByte('[')
+	for i := uint64(0); i < n; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := msgpackvalue(r, buf, depth+1); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+func msgpackmap(r *binreader, buf *bytes.Buffer, n uint64, depth int) error {
+	var key bytes.Buffer
+	buf.WriteByte('{')
+	for i := uint64(0); i < n; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key.Reset()
+		if err := msgpackvalue(r, &key, depth+1); err != nil {
+			return err
+		}
+		writejsonkey(buf, key.Bytes())
+
+		if err := msgpackvalue(r, buf, depth+1); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// msgpackext writes the timestamp extension as the RFC3339 time string,
+// and others as the object {"type":TYPE,"data":BASE64}.
+func msgpackext(r *binreader, buf *bytes.Buffer, n uint64) error {
+	p, err := r.next(1)
+	if err != nil {
+		return err
+	}
+
+	typ := int8(p[0])
+	if p, err = r.next(n); err != nil {
+		return err
+	}
+
+	if typ == -1 {
+		var sec, nsec int64
+		switch len(p) {
+		case 4:
+			sec = int64(bigendian(p))
+		case 8:
+			v := bigendian(p)
+			sec, nsec = int64(v&(1<<34-1)), int64(v>>34)
+		case 12:
+			sec, nsec = int64(bigendian(p[4:])), int64(bigendian(p[:4]))
+		default:
+			return fmt.Errorf("invalid msgpack timestamp length %d", len(p))
+		}
+
+		writejsonvalue(buf, time.Unix(sec, nsec).UTC().Format(time.RFC3339Nano))
+		return nil
+	}
+
+	buf.WriteString(`{"type":`)
+	buf.WriteString(strconv.FormatInt(int64(typ), 10))
+	buf.WriteString(`,"data":`)
+	writejsonvalue(buf, p)
+	buf.WriteByte('}')
+	return nil
+}
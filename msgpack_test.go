@@ -0,0 +1,73 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeMsgpack(t *testing.T) {
+	tests := []struct {
+		data   []byte
+		expect string
+	}{
+		{
+			data: []byte{0x83, 0xa1, 'a', 0x01, 0xa1, 'b', 0x94, 0xc3, 0xc0, 0xff, 0xa1, 'x',
+				0xa1, 'c', 0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0},
+			expect: `{"a":1,"b":[true,null,-1,"x"],"c":1.5}`,
+		},
+		{data: []byte{0x81, 0x01, 0xd0, 0x80}, expect: `{"1":-128}`},
+		{data: []byte{0xcd, 0x01, 0x00}, expect: `256`},
+		{data: []byte{0xd1, 0xff, 0x00}, expect: `-256`},
+		{data: []byte{0xc4, 0x02, 0x01, 0x02}, expect: `"AQI="`},
+		{data: []byte{0xd6, 0xff, 0, 0, 0, 0}, expect: `"1970-01-01T00:00:00Z"`},
+		{data: []byte{0xd4, 0x01, 0x02}, expect: `{"type":1,"data":"Ag=="}`},
+		{data: []byte{0xca, 0x7f, 0xc0, 0, 0}, expect: `"NaN"`},
+	}
+
+	for i, test := range tests {
+		if data, err := decodemsgpack(nil, "request", test.data); err != nil {
+			t.Errorf("%d: unexpected error: %v", i, err)
+		} else if string(data) != test.expect {
+			t.Errorf("%d: expect '%s', but got '%s'", i, test.expect, data)
+		}
+	}
+
+	for i, data := range [][]byte{{0xa5, 'a'}, {0xc1}, {0x01, 0x02}} {
+		if _, err := decodemsgpack(nil, "request", data); err == nil {
+			t.Errorf("%d: expect an error, but got nil", i)
+		}
+	}
+}
+
+func TestMsgpackBody(t *testing.T) {
+	_ = logReqBody.Set(true)
+	defer func() { _ = logReqBody.Set(false) }()
+
+	body := string([]byte{0x81, 0xa1, 'a', 0x01})
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/msgpack")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+
+	if v, ok := attrs["reqbody"].Any().(json.Marshaler); !ok {
+		t.Errorf("expect a raw json reqbody, but got '%v'", attrs["reqbody"])
+	} else if data, _ := v.MarshalJSON(); string(data) != `{"a":1}` {
+		t.Errorf("unexpected reqbody '%s'", data)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sync"
+)
+
+// MultipartPart is the metadata of a part of the multipart/form-data body,
+// which does not contain the content of the part.
+type MultipartPart struct {
+	Name        string `json:"name"`
+	FileName    string `json:"filename,omitempty"`
+	ContentType string `json:"contenttype,omitempty"`
+	Size        int    `json:"size"`
+}
+
+// getmultipartboundary returns the boundary of the multipart/form-data body.
+func getmultipartboundary(header http.Header) (boundary string, ok bool) {
+	mediatype, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediatype != "multipart/form-data" {
+		return
+	}
+	boundary = params["boundary"]
+	return boundary, boundary != ""
+}
+
+// multipartmeta parses the metadata of the parts from the body data
+// written into it in the background.
+type multipartmeta struct {
+	pw    *io.PipeWriter
+	once  sync.Once
+	done  chan struct{}
+	parts []MultipartPart
+}
+
+func newmultipartmeta(boundary string) *multipartmeta {
+	pr, pw := io.Pipe()
+	m := &multipartmeta{pw: pw, done: make(chan struct{})}
+	go m.parse(pr, boundary)
+	return m
+}
+
+func (m *multipartmeta) parse(pr *io.PipeReader, boundary string) {
+	defer close(m.done)
+
+	mr := multipart.NewReader(pr, boundary)
+	for {
+		part, err := mr.NextRawPart()
+		if err != nil {
+			break
+		}
+
+		size, _ := io.Copy(io.Discard, part)
+		m.parts = append(m.parts, MultipartPart{
+			Name:        part.FormName(),
+			FileName:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Size:        int(size),
+		})
+	}
+
+	// Discard the rest, such as the epilogue, not to block the writer.
+	_, _ = io.Copy(io.Discard, pr)
+}
+
+// write writes the body data read by the handler to be parsed.
+func (m *multipartmeta) write(p []byte) { _, _ = m.pw.Write(p) }
+
+// finish stops parsing and returns the metadata of the parsed parts,
+// which may be only a part of them if the handler does not read the whole body.
+func (m *multipartmeta) finish() []MultipartPart {
+	m.once.Do(func() { _ = m.pw.Close() })
+	<-m.done
+	return m.parts
+}
+
+func (m *multipartmeta) attr(key string) slog.Attr {
+	return slog.Any(key+"parts", m.finish())
+}
+
+// multipartBody is used to replace the original multipart/form-data request
+// body, which parses the metadata of the parts as the handler reads it.
+type multipartBody struct {
+	io.Closer
+	body    io.Reader
+	reqbody *reqbody
+}
+
+func (b *multipartBody) Read(p []byte) (n int, err error) {
+	n, err = b.body.Read(p)
+	if n > 0 {
+		b.reqbody.rest += n
+		b.reqbody.hashwrite(p[:n])
+		b.reqbody.multipart.write(p[:n])
+	}
+
+	if err != nil {
+		b.reqbody.done = true
+		if err != io.EOF {
+			b.reqbody.err = err
+		}
+	}
+	return
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMultipartMeta(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logMultipartMeta.Set(true)
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logMultipartMeta.Set(false)
+	}()
+
+	buf := bytes.NewBuffer(nil)
+	mw := multipart.NewWriter(buf)
+	_ = mw.WriteField("name", "abc")
+	fw, _ := mw.CreateFormFile("file", "data.bin")
+	_, _ = fw.Write(bytes.Repeat([]byte{0xff}, 4096))
+	_ = mw.Close()
+	bodylen := buf.Len()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("fail to parse the multipart form: %v", err)
+		} else if v := r.FormValue("name"); v != "abc" {
+			t.Errorf("expect the form value '%s', but got '%s'", "abc", v)
+		}
+	}, req)
+
+	if v := attrs["reqbodylen"].Int64(); v != int64(bodylen) {
+		t.Errorf("expect reqbodylen %d, but got %d", bodylen, v)
+	}
+
+	parts, _ := attrs["reqbodyparts"].Any().([]MultipartPart)
+	expects := []MultipartPart{
+		{Name: "name", Size: 3},
+		{Name: "file", FileName: "data.bin", ContentType: "application/octet-stream", Size: 4096},
+	}
+	if len(parts) != len(expects) {
+		t.Fatalf("expect %d parts, but got %d: %+v", len(expects), len(parts), parts)
+	}
+	for i, part := range parts {
+		if part != expects[i] {
+			t.Errorf("%d: expect part %+v, but got %+v", i, expects[i], part)
+		}
+	}
+
+	if _, ok := attrs["reqbody"]; ok {
+		t.Error("unexpect attr reqbody")
+	}
+	if strings.Contains(attrs["reqbodyparts"].String(), "\xff") {
+		t.Error("unexpect the file content")
+	}
+}
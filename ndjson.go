@@ -0,0 +1,115 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	"github.com/xgfone/go-rawjson"
+)
+
+// isndjsonct reports whether ct is the content type of the streaming JSON,
+// such as application/x-ndjson and application/jsonl.
+func isndjsonct(ct string) bool {
+	return strings.HasSuffix(ct, "ndjson") || strings.HasSuffix(ct, "jsonl") ||
+		strings.HasSuffix(ct, "jsonlines")
+}
+
+// cutlines returns the prefix of p until the remaining lines to buffer,
+// and decreases them.
+//
+// It must be called with the lock held.
+func (r *responseWriter) cutlines(p []byte) []byte {
+	var n int
+	for r.ndjsonlines > 0 {
+		index := bytes.IndexByte(p[n:], '\n')
+		if index < 0 {
+			return p
+		}
+
+		n += index + 1
+		r.ndjsonlines--
+	}
+	return p[:n]
+}
+
+// appendndjsonbody appends the first ndjsonlines lines of the streaming
+// JSON body as an array, whose JSON lines are filtered and redacted
+// like the JSON body, and the others are logged as strings.
+//
+// If data is not the whole body, the last incomplete line is dropped.
+func appendndjsonbody(c *Config, appendAttr func(...slog.Attr), key string, data []byte, size int) {
+	truncated := len(data) < size
+	if truncated {
+		data = data[:bytes.LastIndexByte(data, '\n')+1]
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)+2))
+	buf.WriteByte('[')
+
+	var lines int
+	for len(data) > 0 {
+		var line []byte
+		line, data, _ = bytes.Cut(data, []byte{'\n'})
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		} else if lines == c.NDJSONLines {
+			truncated = true
+			break
+		}
+
+		valid := json.Valid(line)
+		if !valid && len(c.RedactFields) > 0 {
+			return // Not log the line which cannot be redacted to avoid leaking.
+		} else if fields := c.BodyFields; valid && len(fields) > 0 {
+			if line = filterjsonfields(line, fields); line == nil {
+				continue
+			}
+		}
+
+		if valid {
+			line = scrubbody(c, "application/json", line)
+		}
+
+		if lines > 0 {
+			buf.WriteByte(',')
+		}
+		lines++
+
+		switch {
+		case !valid:
+			writejsonvalue(buf, scrubpii(c, string(line)))
+
+		case len(c.RedactFields) > 0 && (line[0] == '{' || line[0] == '['):
+			redacted, err := redactjsonfields(line, c.RedactFields)
+			if err != nil {
+				return
+			}
+			buf.Write(redacted)
+
+		default:
+			buf.Write(line)
+		}
+	}
+	buf.WriteByte(']')
+
+	appendAttr(slog.Any(key, rawjson.Bytes(buf.Bytes())), slog.Int(key+"lines", lines))
+	if truncated {
+		appendAttr(slog.Bool(key+"truncated", true))
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNDJSONBody(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logRespBody.Set(true)
+	_ = logNDJSONLines.Set(2)
+	_ = logRedactFields.Set([]string{"secret"})
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logRespBody.Set(false)
+		_ = logNDJSONLines.Set(0)
+		_ = logRedactFields.Set([]string{})
+	}()
+
+	body := "{\"a\":1,\"secret\":\"x\"}\n\n[1,2]\n"
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, line := range []string{`{"a":1}`, `{"a":2}`, `{"a":3}`} {
+			_, _ = w.Write([]byte(line + "\n"))
+		}
+	}, req)
+
+	expectjson := func(key, expect string) {
+		if v, ok := attrs[key].Any().(json.Marshaler); !ok {
+			t.Errorf("expect a raw json %s, but got '%v'", key, attrs[key])
+		} else if data, _ := v.MarshalJSON(); string(data) != expect {
+			t.Errorf("expect %s '%s', but got '%s'", key, expect, data)
+		}
+	}
+
+	expectjson("reqbody", `[{"a":1,"secret":"***"},[1,2]]`)
+	if _, ok := attrs["reqbodytruncated"]; ok {
+		t.Errorf("unexpected reqbodytruncated")
+	}
+
+	expectjson("respbody", `[{"a":1},{"a":2}]`)
+	if v := attrs["respbodylines"].Int64(); v != 2 {
+		t.Errorf("expect respbodylines %d, but got %d", 2, v)
+	}
+	if v := attrs["respbodytruncated"].Bool(); !v {
+		t.Errorf("expect respbodytruncated, but got %v", attrs["respbodytruncated"])
+	}
+	if v := attrs["respbodylen"].Int64(); v != 24 {
+		t.Errorf("expect respbodylen %d, but got %d", 24, v)
+	}
+}
+
+func TestResponseWriterCutLines(t *testing.T) {
+	rw := &responseWriter{ndjsonlines: 2}
+	if p := rw.cutlines([]byte("a\nb")); string(p) != "a\nb" || rw.ndjsonlines != 1 {
+		t.Errorf("unexpected '%s' with %d lines left", p, rw.ndjsonlines)
+	}
+	if p := rw.cutlines([]byte("c\nd\n")); string(p) != "c\n" || rw.ndjsonlines != 0 {
+		t.Errorf("unexpected '%s' with %d lines left", p, rw.ndjsonlines)
+	}
+}
@@ -0,0 +1,25 @@
+module github.com/xgfone/go-apiserver-middleware-logger-ext/openapiext
+
+require (
+	github.com/getkin/kin-openapi v0.94.0
+	github.com/xgfone/go-apiserver-middleware-logger-ext v0.0.0
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.1 // indirect
+	github.com/ghodss/yaml v1.0.0 // indirect
+	github.com/go-openapi/jsonpointer v0.19.5 // indirect
+	github.com/go-openapi/swag v0.19.5 // indirect
+	github.com/gorilla/mux v1.8.0 // indirect
+	github.com/mailru/easyjson v0.0.0-20190626092158-b2ccc519800e // indirect
+	github.com/xgfone/gconf/v6 v6.5.0 // indirect
+	github.com/xgfone/go-cast v0.8.1 // indirect
+	github.com/xgfone/go-defaults v0.13.0 // indirect
+	github.com/xgfone/go-rawjson v0.1.0 // indirect
+	golang.org/x/text v0.15.0 // indirect
+	gopkg.in/yaml.v2 v2.3.0 // indirect
+)
+
+replace github.com/xgfone/go-apiserver-middleware-logger-ext => ../
+
+go 1.21
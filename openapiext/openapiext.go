@@ -0,0 +1,120 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package openapiext provides the body validator based on
+// "github.com/xgfone/go-apiserver-middleware-logger-ext", which validates
+// the request and response bodies against the OpenAPI 3 specification
+// and reports the violations as the attribute schema_violations.
+package openapiext
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	loggerext "github.com/xgfone/go-apiserver-middleware-logger-ext"
+)
+
+// Validator returns a body validator to validate the request and response
+// bodies against the operations of the OpenAPI 3 document doc,
+// which is loaded by openapi3.Loader, such as LoadFromFile.
+//
+// The request not matching any operation of doc is not validated.
+// And only the bodies are validated, not including the parameters
+// and security requirements.
+func Validator(doc *openapi3.T) (loggerext.BodyValidator, error) {
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &openapi3filter.Options{MultiError: true}
+	return func(r *http.Request, direction string, status int, header http.Header, data []byte) []string {
+		route, params, err := router.FindRoute(r)
+		if err != nil {
+			return nil
+		}
+
+		// Not modify the original request, whose body has been consumed.
+		req := *r
+		req.Body = http.NoBody
+		if len(data) > 0 {
+			req.Body = io.NopCloser(bytes.NewReader(data))
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    &req,
+			PathParams: params,
+			Route:      route,
+			Options:    options,
+		}
+
+		switch direction {
+		case "request":
+			if body := route.Operation.RequestBody; body != nil && body.Value != nil {
+				err = openapi3filter.ValidateRequestBody(r.Context(), input, body.Value)
+			}
+
+		case "response":
+			err = openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 status,
+				Header:                 header,
+				Body:                   io.NopCloser(bytes.NewReader(data)),
+				Options:                options,
+			})
+		}
+
+		if err != nil {
+			return unpack(nil, err)
+		}
+		return nil
+	}, nil
+}
+
+// Register sets the body validator created by Validator.
+func Register(doc *openapi3.T) error {
+	validator, err := Validator(doc)
+	if err == nil {
+		loggerext.SetBodyValidator(validator)
+	}
+	return err
+}
+
+// unpack appends the violations of the validation error err,
+// which is the path of the value and the reason for the schema error.
+func unpack(violations []string, err error) []string {
+	var me openapi3.MultiError
+	if errors.As(err, &me) {
+		for _, err := range me {
+			violations = unpack(violations, err)
+		}
+		return violations
+	}
+
+	var se *openapi3.SchemaError
+	if errors.As(err, &se) {
+		if pointer := se.JSONPointer(); len(pointer) > 0 {
+			return append(violations, "/"+strings.Join(pointer, "/")+": "+se.Reason)
+		}
+		return append(violations, se.Reason)
+	}
+
+	return append(violations, err.Error())
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapiext
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	loggerext "github.com/xgfone/go-apiserver-middleware-logger-ext"
+)
+
+const spec = `{
+  "openapi": "3.0.0",
+  "info": {"title": "test", "version": "1.0.0"},
+  "paths": {
+    "/users": {
+      "post": {
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {
+            "type": "object",
+            "required": ["name"],
+            "properties": {"name": {"type": "string"}, "age": {"type": "integer"}}
+          }}}
+        },
+        "responses": {
+          "201": {
+            "description": "created",
+            "content": {"application/json": {"schema": {
+              "type": "object",
+              "required": ["id"],
+              "properties": {"id": {"type": "integer"}}
+            }}}
+          }
+        }
+      }
+    }
+  }
+}`
+
+func serve(path, reqbody, respbody string) []string {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(reqbody))
+	ctx := loggerext.EnableLogRespBody(loggerext.EnableLogReqBody(req.Context()))
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	w, r := loggerext.WrapReqRespBody(httptest.NewRecorder(), req)
+	defer loggerext.Release(w, r)
+
+	_, _ = io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, _ = io.WriteString(w, respbody)
+
+	var violations []string
+	loggerext.Collect(w, r, func(attrs ...slog.Attr) {
+		for _, attr := range attrs {
+			if attr.Key == "schema_violations" {
+				violations, _ = attr.Value.Any().([]string)
+			}
+		}
+	})
+	return violations
+}
+
+func TestRegister(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Register(doc); err != nil {
+		t.Fatal(err)
+	}
+	defer loggerext.SetBodyValidator(nil)
+
+	if v := serve("/users", `{"name":"abc","age":18}`, `{"id":1}`); v != nil {
+		t.Errorf("unexpected violations %v", v)
+	}
+
+	expect := []string{
+		`request: /age: Field must be set to integer or not be present`,
+		`response: /id: Field must be set to integer or not be present`,
+	}
+	if v := serve("/users", `{"name":"abc","age":"18"}`, `{"id":"1"}`); !slices.Equal(v, expect) {
+		t.Errorf("expect violations %q, but got %q", expect, v)
+	}
+
+	if v := serve("/unknown", `{}`, `{}`); v != nil {
+		t.Errorf("unexpected violations %v", v)
+	}
+}
@@ -0,0 +1,25 @@
+module github.com/xgfone/go-apiserver-middleware-logger-ext/otelext
+
+require (
+	github.com/xgfone/go-apiserver-middleware-logger-ext v0.0.0
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/sdk v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.1 // indirect
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/xgfone/gconf/v6 v6.5.0 // indirect
+	github.com/xgfone/go-cast v0.8.1 // indirect
+	github.com/xgfone/go-defaults v0.13.0 // indirect
+	github.com/xgfone/go-rawjson v0.1.0 // indirect
+	go.opentelemetry.io/otel/metric v1.24.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+	golang.org/x/text v0.15.0 // indirect
+)
+
+replace github.com/xgfone/go-apiserver-middleware-logger-ext => ../
+
+go 1.21
@@ -0,0 +1,42 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otelext provides the OpenTelemetry integration based on
+// "github.com/xgfone/go-apiserver-middleware-logger-ext".
+//
+// Importing the package registers TraceIDs by loggerext.SetTraceGetter,
+// so that loggerext.Collect appends the attributes trace_id and span_id
+// of the active span in the request context.
+//
+//	import _ "github.com/xgfone/go-apiserver-middleware-logger-ext/otelext"
+package otelext
+
+import (
+	"context"
+
+	loggerext "github.com/xgfone/go-apiserver-middleware-logger-ext"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func init() { loggerext.SetTraceGetter(TraceIDs) }
+
+// TraceIDs returns the trace id and span id of the valid span context
+// in ctx. If no valid span context, return ("", "").
+func TraceIDs(ctx context.Context) (traceID, spanID string) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	return sc.TraceID().String(), sc.SpanID().String()
+}
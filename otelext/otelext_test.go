@@ -0,0 +1,55 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelext
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	loggerext "github.com/xgfone/go-apiserver-middleware-logger-ext"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceIDs(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+
+	req := httptest.NewRequest(http.MethodGet, "/path", nil)
+	attrs := make(map[string]string)
+	collect := func(r *http.Request) {
+		clear(attrs)
+		loggerext.Collect(httptest.NewRecorder(), r, func(as ...slog.Attr) {
+			for _, a := range as {
+				attrs[a.Key] = a.Value.String()
+			}
+		})
+	}
+
+	collect(req)
+	if _, ok := attrs["trace_id"]; ok {
+		t.Errorf("unexpected trace_id without span: %v", attrs)
+	}
+
+	collect(req.WithContext(trace.ContextWithSpanContext(req.Context(), sc)))
+	if v := attrs["trace_id"]; v != traceID.String() {
+		t.Errorf("expect trace_id '%s', but got '%s'", traceID, v)
+	}
+	if v := attrs["span_id"]; v != spanID.String() {
+		t.Errorf("expect span_id '%s', but got '%s'", spanID, v)
+	}
+}
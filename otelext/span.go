@@ -0,0 +1,113 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelext
+
+import (
+	"encoding"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	loggerext "github.com/xgfone/go-apiserver-middleware-logger-ext"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanEventName is the name of the span event added by AddSpanEvent.
+const SpanEventName = "http.capture"
+
+// RecordSpanEvents registers AddSpanEvent by loggerext.SetCollectHook,
+// so that the attributes collected by loggerext.Collect, such as the redacted
+// and truncated headers and bodies, are also added into the active span
+// in addition to the log record.
+func RecordSpanEvents() { loggerext.SetCollectHook(AddSpanEvent) }
+
+// Middleware returns a http middleware, which wraps the request and response
+// like loggerext.WrapHandler, and adds the collected attributes into
+// the active span in the request context instead of the log record.
+//
+// The span must be started by the previous middleware, such as otelhttp.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !trace.SpanFromContext(r.Context()).IsRecording() || !loggerext.Enabled(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w, r = loggerext.WrapReqRespBody(w, r)
+		defer loggerext.Release(w, r)
+		next.ServeHTTP(w, r)
+
+		attrs := make([]slog.Attr, 0, 16)
+		loggerext.Collect(w, r, func(as ...slog.Attr) { attrs = append(attrs, as...) })
+		if len(attrs) > 0 {
+			AddSpanEvent(r, attrs)
+		}
+	})
+}
+
+// AddSpanEvent adds the attributes as the span event named SpanEventName
+// into the recording span in the request context.
+func AddSpanEvent(r *http.Request, attrs []slog.Attr) {
+	span := trace.SpanFromContext(r.Context())
+	if !span.IsRecording() {
+		return
+	}
+
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		kvs = appendkvs(kvs, "", attr)
+	}
+	span.AddEvent(SpanEventName, trace.WithAttributes(kvs...))
+}
+
+// appendkvs converts the slog attribute to the span attributes,
+// and the group is flattened with the dotted keys, such as "cookies.sid".
+func appendkvs(kvs []attribute.KeyValue, prefix string, attr slog.Attr) []attribute.KeyValue {
+	key := prefix + attr.Key
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindGroup:
+		for _, a := range value.Group() {
+			kvs = appendkvs(kvs, key+".", a)
+		}
+		return kvs
+	case slog.KindString:
+		// Clone the string since the body buffer will be released.
+		return append(kvs, attribute.String(key, strings.Clone(value.String())))
+	case slog.KindInt64:
+		return append(kvs, attribute.Int64(key, value.Int64()))
+	case slog.KindFloat64:
+		return append(kvs, attribute.Float64(key, value.Float64()))
+	case slog.KindBool:
+		return append(kvs, attribute.Bool(key, value.Bool()))
+	case slog.KindAny:
+		// Such as the JSON body, which references the body buffer, too.
+		switch v := value.Any().(type) {
+		case json.Marshaler:
+			if data, err := v.MarshalJSON(); err == nil {
+				return append(kvs, attribute.String(key, string(data)))
+			}
+		case encoding.TextMarshaler:
+			if data, err := v.MarshalText(); err == nil {
+				return append(kvs, attribute.String(key, string(data)))
+			}
+		}
+		return append(kvs, attribute.String(key, value.String()))
+	default:
+		return append(kvs, attribute.String(key, value.String()))
+	}
+}
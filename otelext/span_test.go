@@ -0,0 +1,114 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelext
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	loggerext "github.com/xgfone/go-apiserver-middleware-logger-ext"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write(body)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("abc"))
+	req.Header.Set("Content-Type", "text/plain")
+	ctx := loggerext.EnableLogRespBody(loggerext.EnableLogReqBody(req.Context()))
+	ctx, span := tracer.Start(ctx, "request")
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || len(spans[0].Events()) != 1 {
+		t.Fatalf("expect one span with one event, but got %v", spans)
+	}
+
+	event := spans[0].Events()[0]
+	if event.Name != SpanEventName {
+		t.Errorf("expect the event '%s', but got '%s'", SpanEventName, event.Name)
+	}
+
+	attrs := make(map[string]string)
+	for _, kv := range event.Attributes {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if v := attrs["reqbody"]; v != "abc" {
+		t.Errorf("expect reqbody '%s', but got '%s'", "abc", v)
+	}
+	if v := attrs["respbody"]; v != "abc" {
+		t.Errorf("expect respbody '%s', but got '%s'", "abc", v)
+	}
+	if v := attrs["trace_id"]; v != span.SpanContext().TraceID().String() {
+		t.Errorf("expect trace_id '%s', but got '%s'", span.SpanContext().TraceID(), v)
+	}
+}
+
+func TestMiddlewareJSON(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(`{"name":"abc"}`))
+	req.Header.Set("Content-Type", "application/json")
+	ctx := loggerext.EnableLogRespBody(loggerext.EnableLogReqBody(req.Context()))
+	ctx, span := tracer.Start(ctx, "request")
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || len(spans[0].Events()) != 1 {
+		t.Fatalf("expect one span with one event, but got %v", spans)
+	}
+
+	attrs := make(map[string]string)
+	for _, kv := range spans[0].Events()[0].Attributes {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if v := attrs["reqbody"]; v != `{"name":"abc"}` {
+		t.Errorf("expect reqbody '%s', but got '%s'", `{"name":"abc"}`, v)
+	}
+	if v := attrs["respbody"]; v != `{"id":1}` {
+		t.Errorf("expect respbody '%s', but got '%s'", `{"id":1}`, v)
+	}
+}
+
+func TestMiddlewareWithoutSpan(t *testing.T) {
+	var called bool
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+	req := httptest.NewRequest(http.MethodGet, "/path", nil).WithContext(context.Background())
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if !called {
+		t.Error("expect the handler to be called")
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// PIIPattern is used to detect the PII (Personally Identifiable Information)
+// in the bodies and headers, such as the email addresses.
+type PIIPattern struct {
+	Regexp *regexp.Regexp
+
+	// Valid is optional and reports whether the matched value is really
+	// the PII, such as by the checksum, to reduce the false positives.
+	Valid func(match string) bool
+}
+
+var piipatterns = map[string]PIIPattern{
+	"email": {Regexp: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	"card":  {Regexp: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), Valid: isluhn},
+	"iban": {
+		Regexp: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+		Valid:  isiban,
+	},
+	"phone": {
+		Regexp: regexp.MustCompile(`\+\d{1,3}[ .-]?(?:\(\d{1,4}\)|\d{1,4})(?:[ .-]?\d{2,4}){1,4}\b` +
+			`|\(\d{3}\)[ .-]?\d{3}[ .-]\d{4}\b|\b\d{3}[.-]\d{3}[.-]\d{4}\b`),
+		Valid: func(match string) bool { n := countdigits(match); return n >= 8 && n <= 15 },
+	},
+}
+
+// SetPIIPattern sets the PII pattern with the name, which is enabled
+// by the option scrubpii.
+//
+// The patterns email, card, iban and phone are registered by default.
+// The card numbers are verified by Luhn, and IBANs by the MOD 97 checksum.
+//
+// If pattern.Regexp is nil, unset it.
+func SetPIIPattern(name string, pattern PIIPattern) {
+	if pattern.Regexp == nil {
+		delete(piipatterns, name)
+	} else {
+		piipatterns[name] = pattern
+	}
+}
+
+// scrubpii replaces the PII in s detected by the patterns configured
+// by the option scrubpii in order with RedactedValue.
+func scrubpii(c *Config, s string) string {
+	for _, name := range c.ScrubPII {
+		pattern, ok := piipatterns[name]
+		if !ok {
+			continue
+		}
+
+		s = pattern.Regexp.ReplaceAllStringFunc(s, func(match string) string {
+			if pattern.Valid == nil || pattern.Valid(match) {
+				return RedactedValue
+			}
+			return match
+		})
+	}
+	return s
+}
+
+// scrubbody returns the body whose PII is replaced by scrubpii.
+//
+// For the JSON body, only the string and number values are scrubbed,
+// and the scrubbed number is replaced with the string RedactedValue.
+func scrubbody(c *Config, ct string, data []byte) []byte {
+	if len(c.ScrubPII) == 0 || len(data) == 0 {
+		return data
+	}
+
+	if trimmed := bytes.TrimLeft(data, jsonspace); isjsonct(ct) && len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		dec.UseNumber()
+
+		buf := bytes.NewBuffer(make([]byte, 0, len(data)))
+		if err := scrubjsonvalue(c, buf, dec); err == nil {
+			return buf.Bytes()
+		}
+	}
+
+	if s := scrubpii(c, string(data)); s != string(data) {
+		return []byte(s)
+	}
+	return data
+}
+
+func scrubjsonvalue(c *Config, buf *bytes.Buffer, dec *json.Decoder) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		buf.WriteByte(byte(token))
+		for i := 0; dec.More(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if token == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				writejsonvalue(buf, key)
+				buf.WriteByte(':')
+			}
+
+			if err := scrubjsonvalue(c, buf, dec); err != nil {
+				return err
+			}
+		}
+
+		end, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(byte(end.(json.Delim)))
+
+	case string:
+		writejsonvalue(buf, scrubpii(c, token))
+
+	case json.Number:
+		if s := scrubpii(c, string(token)); s != string(token) {
+			writejsonvalue(buf, s)
+		} else {
+			writejsonvalue(buf, token)
+		}
+
+	default:
+		writejsonvalue(buf, token)
+	}
+
+	return nil
+}
+
+// scrubheaders replaces the PII of the header values by scrubpii,
+// which are the values of redacted if not nil, or header.
+func scrubheaders(c *Config, header, redacted http.Header) http.Header {
+	src := redacted
+	if src == nil {
+		src = header
+	}
+
+	for name, values := range src {
+		for i, value := range values {
+			if s := scrubpii(c, value); s != value {
+				if redacted == nil {
+					redacted = header.Clone()
+				}
+				redacted[name][i] = s
+			}
+		}
+	}
+	return redacted
+}
+
+func countdigits(s string) (n int) {
+	for i := 0; i < len(s); i++ {
+		if '0' <= s[i] && s[i] <= '9' {
+			n++
+		}
+	}
+	return
+}
+
+// isluhn reports whether the card number passes the Luhn checksum.
+func isluhn(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue // Skip the separators.
+		}
+
+		digit := int(s[i] - '0')
+		if n%2 == 1 {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		n++
+	}
+	return n >= 13 && n <= 19 && sum%10 == 0
+}
+
+// isiban reports whether the IBAN passes the MOD 97 checksum.
+func isiban(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+
+	// Move the first four characters to the end, and replace the letters
+	// with the numbers, such as A=10 and Z=35.
+	var remainder int
+	for _, r := range s[4:] + s[:4] {
+		if r >= 'A' && r <= 'Z' {
+			remainder = (remainder*100 + int(r-'A'+10)) % 97
+		} else {
+			remainder = (remainder*10 + int(r-'0')) % 97
+		}
+	}
+	return remainder == 1
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestScrubPII(t *testing.T) {
+	c := globalconfig()
+	c.ScrubPII = []string{"email", "card", "iban", "phone"}
+
+	for s, expect := range map[string]string{
+		"contact alice@example.com now":                          "contact *** now",
+		"card 4111 1111 1111 1111 ok":                            "card *** ok",
+		"card 4111-1111-1111-1112 ok":                            "card 4111-1111-1111-1112 ok",
+		"iban GB82 WEST 1234 5698 7654 32":                       "iban ***",
+		"iban DE89370400440532013000.":                           "iban ***.",
+		"iban DE00370400440532013000":                            "iban DE00370400440532013000",
+		"call +1 415-555-2671 or (415) 555-2671 or 415.555.2671": "call *** or *** or ***",
+		"date 2024-01-15 and id 1700000000":                      "date 2024-01-15 and id 1700000000",
+	} {
+		if v := scrubpii(c, s); v != expect {
+			t.Errorf("%s: expect '%s', but got '%s'", s, expect, v)
+		}
+	}
+
+	data := []byte(`{"email":"a@b.com","card":4111111111111111,"items":[{"id":1}],"ok":true}`)
+	expect := `{"email":"***","card":"***","items":[{"id":1}],"ok":true}`
+	attr, ok := getbodyattr(c, data, "reqbody", "application/json")
+	if !ok {
+		t.Fatal("expect to log the body, but got not")
+	}
+	if body, _ := attr.Value.Any().(json.Marshaler).MarshalJSON(); string(body) != expect {
+		t.Errorf("expect '%s', but got '%s'", expect, body)
+	}
+
+	header := http.Header{"From": {"a@b.com"}, "Accept": {"*/*"}}
+	if v := redactheaders(c, header); v.Get("From") != RedactedValue || v.Get("Accept") != "*/*" {
+		t.Errorf("unexpected scrubbed headers %v", v)
+	}
+	if header.Get("From") != "a@b.com" {
+		t.Errorf("unexpected the modified original header %v", header)
+	}
+}
+
+func TestSetPIIPattern(t *testing.T) {
+	SetPIIPattern("ssn", PIIPattern{Regexp: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)})
+	defer SetPIIPattern("ssn", PIIPattern{})
+
+	c := globalconfig()
+	c.ScrubPII = []string{"ssn"}
+	if v := scrubpii(c, "ssn 123-45-6789"); v != "ssn ***" {
+		t.Errorf("expect '%s', but got '%s'", "ssn ***", v)
+	}
+
+	c.ScrubPII = []string{"unknown"}
+	if err := c.Validate(); err == nil {
+		t.Error("expect an error, but got nil")
+	}
+}
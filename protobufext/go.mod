@@ -0,0 +1,19 @@
+module github.com/xgfone/go-apiserver-middleware-logger-ext/protobufext
+
+require (
+	github.com/xgfone/go-apiserver-middleware-logger-ext v0.0.0
+	google.golang.org/protobuf v1.34.1
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.1 // indirect
+	github.com/xgfone/gconf/v6 v6.5.0 // indirect
+	github.com/xgfone/go-cast v0.8.1 // indirect
+	github.com/xgfone/go-defaults v0.13.0 // indirect
+	github.com/xgfone/go-rawjson v0.1.0 // indirect
+	golang.org/x/text v0.15.0 // indirect
+)
+
+replace github.com/xgfone/go-apiserver-middleware-logger-ext => ../
+
+go 1.21
@@ -0,0 +1,78 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package protobufext provides the protobuf decoder of the request and
+// response bodies based on "github.com/xgfone/go-apiserver-middleware-logger-ext",
+// which logs the protobuf bodies as JSON.
+package protobufext
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	loggerext "github.com/xgfone/go-apiserver-middleware-logger-ext"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentTypes is the content types of the protobuf bodies registered by Register.
+var ContentTypes = []string{"application/x-protobuf", "application/protobuf"}
+
+// ErrUnknownMessage is returned when newmsg returns nil.
+var ErrUnknownMessage = errors.New("unknown protobuf message")
+
+// Decoder returns a content decoder to decode the protobuf body into JSON,
+// which uses newmsg to create the message of the request or response,
+// such as by the request path.
+//
+// direction is either "request" or "response".
+func Decoder(newmsg func(r *http.Request, direction string) proto.Message) loggerext.ContentDecoder {
+	if newmsg == nil {
+		panic("protobufext: the message creator must not be nil")
+	}
+
+	return func(r *http.Request, direction string, data []byte) ([]byte, error) {
+		msg := newmsg(r, direction)
+		if msg == nil {
+			return nil, ErrUnknownMessage
+		}
+
+		if err := proto.Unmarshal(data, msg); err != nil {
+			return nil, err
+		}
+
+		data, err := protojson.Marshal(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		// The output of protojson is unstable deliberately, so compact it.
+		var buf bytes.Buffer
+		if err = json.Compact(&buf, data); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+// Register sets the protobuf decoder created by Decoder
+// for the content types in ContentTypes.
+func Register(newmsg func(r *http.Request, direction string) proto.Message) {
+	decoder := Decoder(newmsg)
+	for _, ct := range ContentTypes {
+		loggerext.SetContentDecoder(ct, decoder)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protobufext
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	loggerext "github.com/xgfone/go-apiserver-middleware-logger-ext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func newmsg(r *http.Request, direction string) proto.Message {
+	if direction == "request" {
+		return new(structpb.Struct)
+	}
+	return nil
+}
+
+func TestDecoder(t *testing.T) {
+	msg, _ := structpb.NewStruct(map[string]any{"name": "abc"})
+	data, _ := proto.Marshal(msg)
+
+	decoder := Decoder(newmsg)
+	if v, err := decoder(nil, "request", data); err != nil {
+		t.Error(err)
+	} else if s := string(v); s != `{"name":"abc"}` {
+		t.Errorf("expect '%s', but got '%s'", `{"name":"abc"}`, s)
+	}
+
+	if _, err := decoder(nil, "response", data); err != ErrUnknownMessage {
+		t.Errorf("expect error ErrUnknownMessage, but got %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register(newmsg)
+	defer func() {
+		for _, ct := range ContentTypes {
+			loggerext.SetContentDecoder(ct, nil)
+		}
+	}()
+
+	msg, _ := structpb.NewStruct(map[string]any{"name": "abc"})
+	data, _ := proto.Marshal(msg)
+
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(string(data)))
+	req = req.WithContext(loggerext.EnableLogReqBody(req.Context()))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+
+	w, r := loggerext.WrapReqRespBody(httptest.NewRecorder(), req)
+	defer loggerext.Release(w, r)
+
+	var reqbody slog.Value
+	loggerext.Collect(w, r, func(attrs ...slog.Attr) {
+		for _, attr := range attrs {
+			if attr.Key == "reqbody" {
+				reqbody = attr.Value
+			}
+		}
+	})
+
+	m, ok := reqbody.Any().(json.Marshaler)
+	if !ok {
+		t.Fatalf("expect a json reqbody, but got %v", reqbody)
+	}
+	if v, _ := m.MarshalJSON(); string(v) != `{"name":"abc"}` {
+		t.Errorf("expect reqbody '%s', but got '%s'", `{"name":"abc"}`, v)
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RedactedValue is the placeholder to replace the redacted value.
+const RedactedValue = "***"
+
+var queryRedactor = func(key, value string) string { return RedactedValue }
+
+// SetQueryRedactor sets the redactor to mask the value of the query key
+// configured by the option redactqueries, such as hashing it.
+// The value passed to redactor is unescaped, and the result is used as-is.
+//
+// Default: replace the value with RedactedValue.
+func SetQueryRedactor(redactor func(key, value string) string) {
+	if redactor == nil {
+		panic("SetQueryRedactor: the query redactor must not be nil")
+	}
+	queryRedactor = redactor
+}
+
+// redactquery replaces the values of the query keys configured
+// by the option redactqueries by the query redactor, and keeps the others as-is.
+func redactquery(c *Config, query string) string {
+	keys := c.RedactQueries
+	if len(keys) == 0 || query == "" {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query))
+	for i, pair := range strings.Split(query, "&") {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+
+		key, value, hasvalue := strings.Cut(pair, "=")
+		if _key, err := url.QueryUnescape(key); err == nil {
+			key = _key
+		}
+
+		if hasvalue && containsfold(keys, key) {
+			if _value, err := url.QueryUnescape(value); err == nil {
+				value = _value
+			}
+
+			b.WriteString(pair[:strings.IndexByte(pair, '=')+1])
+			b.WriteString(queryRedactor(key, value))
+		} else {
+			b.WriteString(pair)
+		}
+	}
+	return b.String()
+}
+
+var headerRedactor = func(name, value string) string { return RedactedValue }
+
+// SetHeaderRedactor sets the redactor to mask the value of the header
+// configured by the option redactheaders, such as hashing it.
+//
+// Default: replace the value with RedactedValue.
+func SetHeaderRedactor(redactor func(name, value string) string) {
+	if redactor == nil {
+		panic("SetHeaderRedactor: the header redactor must not be nil")
+	}
+	headerRedactor = redactor
+}
+
+// redactheaders returns a copy of the headers whose values configured
+// by the option redactheaders are redacted, and the authorization headers
+// are masked by the option authmask, or the original if no redaction.
+func redactheaders(c *Config, header http.Header) http.Header {
+	names := c.RedactHeaders
+	authmask := c.AuthMask != "" && c.AuthMask != "redact"
+	if len(names) == 0 && !authmask && c.DebugHeader == "" && len(c.ScrubPII) == 0 {
+		return header
+	}
+
+	var redacted http.Header
+	for name, values := range header {
+		isauth := authmask && isauthheader(name)
+		isdebug := c.DebugHeader != "" && strings.EqualFold(name, c.DebugHeader)
+		if !isauth && !isdebug && !containsfold(names, name) {
+			continue
+		}
+
+		if redacted == nil {
+			redacted = header.Clone()
+		}
+
+		if isauth && c.AuthMask == "drop" {
+			delete(redacted, name)
+			continue
+		}
+
+		_values := make([]string, len(values))
+		for i, value := range values {
+			if isdebug {
+				_values[i] = RedactedValue // Never leak the debug token.
+			} else if isauth {
+				_values[i] = maskauth(c, name, value)
+			} else {
+				_values[i] = headerRedactor(name, value)
+			}
+		}
+		redacted[name] = _values
+	}
+
+	if len(c.ScrubPII) > 0 {
+		redacted = scrubheaders(c, header, redacted)
+	}
+
+	if redacted == nil {
+		return header
+	}
+	return redacted
+}
+
+func isauthheader(name string) bool {
+	return strings.EqualFold(name, "Authorization") || strings.EqualFold(name, "Proxy-Authorization")
+}
+
+// maskauth masks the value of the authorization header by the option authmask.
+func maskauth(c *Config, name, value string) string {
+	switch c.AuthMask {
+	case "scheme":
+		// Keep only the scheme, such as "Bearer ***".
+		if scheme, _, ok := strings.Cut(value, " "); ok {
+			return scheme + " " + RedactedValue
+		}
+		return RedactedValue
+
+	case "prefix":
+		// Keep the scheme and the short prefix of the credentials,
+		// such as "Bearer eyJh***".
+		scheme, credentials, ok := strings.Cut(value, " ")
+		if !ok {
+			scheme, credentials = "", value
+		} else {
+			scheme += " "
+		}
+
+		if n := c.AuthPrefixLen; n > 0 && n < len(credentials) {
+			return scheme + credentials[:n] + RedactedValue
+		}
+		return scheme + RedactedValue
+
+	case "hash":
+		return HashRedactor(name, value)
+
+	default:
+		return headerRedactor(name, value)
+	}
+}
+
+// HashRedactor is a redactor to replace the value with the prefix
+// of its hex-encoded SHA-256 hash, such as "sha256:2cf24dba5fb0a30e",
+// which can be used by SetHeaderRedactor, SetQueryRedactor
+// and SetCookieRedactor to correlate the values without leaking them.
+func HashRedactor(name, value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+var cookieRedactor = func(name, value string) string { return RedactedValue }
+
+// SetCookieRedactor sets the redactor to mask the value of the cookie
+// configured by the option redactcookies, such as HashRedactor.
+//
+// Default: replace the value with RedactedValue.
+func SetCookieRedactor(redactor func(name, value string) string) {
+	if redactor == nil {
+		panic("SetCookieRedactor: the cookie redactor must not be nil")
+	}
+	cookieRedactor = redactor
+}
+
+// getcookiesattr returns the attribute of the request cookies,
+// whose values configured by the option redactcookies are redacted.
+func getcookiesattr(c *Config, r *http.Request) (attr slog.Attr, ok bool) {
+	cookies := r.Cookies()
+	if len(cookies) == 0 {
+		return
+	}
+
+	attrs := make([]any, len(cookies))
+	for i, cookie := range cookies {
+		value := cookie.Value
+		if containsfold(c.RedactCookies, cookie.Name) {
+			value = cookieRedactor(cookie.Name, value)
+		}
+		attrs[i] = slog.String(cookie.Name, value)
+	}
+	return slog.Group("cookies", attrs...), true
+}
+
+func containsfold(ss []string, s string) bool {
+	for _, _s := range ss {
+		if strings.EqualFold(_s, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// getrequesturi returns the request uri applying the same query rules
+// as the query attribute, that's, the query is excluded if the option
+// query is disabled, or redacted by the option redactqueries.
+func getrequesturi(c *Config, r *http.Request) string {
+	path := r.URL.EscapedPath()
+	if r.URL.RawQuery == "" || !c.LogQuery {
+		return path
+	}
+	return path + "?" + redactquery(c, r.URL.RawQuery)
+}
+
+// redactbody returns the body filtered and redacted like the logged body
+// by the options bodyfields, ctbodyfields, redactfields, redactxml,
+// redactqueries for formbody, and scrubpii, which is used by the sinks
+// other than the log, such as the flight recorder and capturedir.
+//
+// If the body cannot be redacted, such as being truncated, ok is false,
+// and it must not be exposed.
+func redactbody(c *Config, data []byte, ct string) (_ []byte, ok bool) {
+	if len(data) == 0 {
+		return data, true
+	}
+
+	var err error
+	c = c.forct(ct)
+	switch data = scrubbody(c, ct, data); {
+	case isjsonct(ct) && (len(c.BodyFields) > 0 || len(c.RedactFields) > 0):
+		if !json.Valid(data) {
+			return nil, false // Such as the truncated JSON.
+		}
+
+		if fields := c.BodyFields; len(fields) > 0 {
+			if data = filterjsonfields(data, fields); data == nil {
+				return nil, false // Not a JSON object.
+			}
+		}
+
+		if len(c.RedactFields) > 0 {
+			data, err = redactjsonfields(data, c.RedactFields)
+		}
+
+	case isxmlct(ct) && len(c.RedactXML) > 0:
+		data, err = transformxml(data, c.RedactXML, false, "")
+
+	case isformct(ct) && c.FormBody && len(c.RedactQueries) > 0:
+		var form url.Values
+		if form, err = url.ParseQuery(string(data)); err == nil {
+			redactform(c, form)
+			data = []byte(form.Encode())
+		}
+	}
+
+	return data, err == nil
+}
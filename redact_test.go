@@ -0,0 +1,203 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedactQuery(t *testing.T) {
+	defer func(keys []string) { _ = logRedactQueries.Set(keys) }(logRedactQueries.Get())
+	_ = logRedactQueries.Set([]string{"token"})
+
+	if q := redactquery(globalconfig(), "a=1&Token=abc&b&token"); q != "a=1&Token=***&b&token" {
+		t.Errorf("unexpected redacted query '%s'", q)
+	}
+}
+
+func TestRequestLine(t *testing.T) {
+	defer func(keys []string) { _ = logRedactQueries.Set(keys) }(logRedactQueries.Get())
+	_ = logRequestLine.Set(true)
+	_ = logRedactQueries.Set([]string{"token"})
+	defer func() {
+		_ = logQuery.Set(false)
+		_ = logRequestLine.Set(false)
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/path?a=1&token=secret", nil)
+	if v := collectAttrs(httptest.NewRecorder(), req)["requestline"].String(); v != "GET /path HTTP/1.1" {
+		t.Errorf("unexpected request line '%s'", v)
+	}
+
+	_ = logQuery.Set(true)
+	attrs := collectAttrs(httptest.NewRecorder(), req)
+	if v := attrs["requestline"].String(); v != "GET /path?a=1&token=*** HTTP/1.1" {
+		t.Errorf("unexpected request line '%s'", v)
+	}
+	if v := attrs["query"].String(); v != "a=1&token=***" {
+		t.Errorf("unexpected query '%s'", v)
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer token")
+	header.Set("X-Api-Key", "key")
+	header.Set("Accept", "*/*")
+
+	redacted := redactheaders(globalconfig(), header)
+	if v := redacted.Get("Authorization"); v != RedactedValue {
+		t.Errorf("expect Authorization '%s', but got '%s'", RedactedValue, v)
+	}
+	if v := redacted.Get("X-Api-Key"); v != RedactedValue {
+		t.Errorf("expect X-Api-Key '%s', but got '%s'", RedactedValue, v)
+	}
+	if v := redacted.Get("Accept"); v != "*/*" {
+		t.Errorf("expect Accept '%s', but got '%s'", "*/*", v)
+	}
+	if v := header.Get("Authorization"); v != "Bearer token" {
+		t.Errorf("the original header is modified: %s", v)
+	}
+
+	SetHeaderRedactor(func(name, value string) string { return name + ":" + RedactedValue })
+	defer SetHeaderRedactor(func(name, value string) string { return RedactedValue })
+	if v := redactheaders(globalconfig(), header).Get("X-Api-Key"); v != "X-Api-Key:***" {
+		t.Errorf("expect X-Api-Key '%s', but got '%s'", "X-Api-Key:***", v)
+	}
+
+	header.Del("Authorization")
+	header.Del("X-Api-Key")
+	if redacted := redactheaders(globalconfig(), header); len(redacted) != 1 {
+		t.Errorf("unexpected headers %v", redacted)
+	}
+}
+
+func TestAuthMask(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer eyJhbGciOi")
+	header.Set("Accept", "*/*")
+
+	c := globalconfig()
+	c.RedactHeaders = nil
+
+	for _, tc := range []struct {
+		mask   string
+		expect string
+	}{
+		{"redact", "Bearer eyJhbGciOi"},
+		{"scheme", "Bearer ***"},
+		{"prefix", "Bearer eyJh***"},
+		{"hash", HashRedactor("Authorization", "Bearer eyJhbGciOi")},
+		{"drop", ""},
+	} {
+		c.AuthMask = tc.mask
+		if v := redactheaders(c, header).Get("Authorization"); v != tc.expect {
+			t.Errorf("%s: expect Authorization '%s', but got '%s'", tc.mask, tc.expect, v)
+		}
+	}
+
+	if v := header.Get("Authorization"); v != "Bearer eyJhbGciOi" {
+		t.Errorf("the original header is modified: %s", v)
+	}
+}
+
+func TestQueryRedactor(t *testing.T) {
+	if q := redactquery(globalconfig(), "a=1&api_key=abc"); q != "a=1&api_key=***" {
+		t.Errorf("unexpected redacted query '%s'", q)
+	}
+
+	SetQueryRedactor(func(key, value string) string { return "<" + value + ">" })
+	defer SetQueryRedactor(func(key, value string) string { return RedactedValue })
+
+	if q := redactquery(globalconfig(), "token=a%20b&b=2"); q != "token=<a b>&b=2" {
+		t.Errorf("unexpected redacted query '%s'", q)
+	}
+}
+
+func TestCookies(t *testing.T) {
+	_ = logCookies.Set(true)
+	defer func() { _ = logCookies.Set(false) }()
+
+	req := httptest.NewRequest(http.MethodGet, "/path", nil)
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	req.AddCookie(&http.Cookie{Name: "SessionID", Value: "abc"})
+
+	cookies := make(map[string]string)
+	for _, attr := range collectAttrs(httptest.NewRecorder(), req)["cookies"].Group() {
+		cookies[attr.Key] = attr.Value.String()
+	}
+
+	if v := cookies["theme"]; v != "dark" {
+		t.Errorf("expect cookie theme '%s', but got '%s'", "dark", v)
+	}
+	if v := cookies["SessionID"]; v != RedactedValue {
+		t.Errorf("expect cookie SessionID '%s', but got '%s'", RedactedValue, v)
+	}
+
+	SetCookieRedactor(HashRedactor)
+	defer SetCookieRedactor(func(name, value string) string { return RedactedValue })
+	if v := collectAttrs(httptest.NewRecorder(), req)["cookies"].Group()[1].Value.String(); v != "sha256:ba7816bf8f01cfea" {
+		t.Errorf("expect cookie SessionID '%s', but got '%s'", "sha256:ba7816bf8f01cfea", v)
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	c := globalconfig()
+	c.RedactFields = []string{"password"}
+	c.RedactXML = []string{"user.password"}
+	c.RedactQueries = []string{"password"}
+	c.FormBody = true
+
+	for _, v := range []struct {
+		ct     string
+		data   string
+		expect string
+		ok     bool
+	}{
+		{"application/json", `{"name":"abc","password":"123"}`, `{"name":"abc","password":"***"}`, true},
+		{"application/json", `{"name":"abc","pass`, "", false},
+		{"application/xml", `<user><password>123</password></user>`, `<user><password>***</password></user>`, true},
+		{"application/x-www-form-urlencoded", `password=123&name=abc`, `name=abc&password=%2A%2A%2A`, true},
+		{"text/plain", `password=123`, `password=123`, true},
+	} {
+		data, ok := redactbody(c, []byte(v.data), v.ct)
+		if ok != v.ok || (ok && string(data) != v.expect) {
+			t.Errorf("%s: expect '%s' and %v, but got '%s' and %v", v.ct, v.expect, v.ok, data, ok)
+		}
+	}
+}
+
+func TestRedactBodyFields(t *testing.T) {
+	c := globalconfig()
+	c.BodyFields = []string{"id", "password"}
+	c.CTBodyFields = []string{"application/vnd.user+json=name"}
+	c.RedactFields = []string{"password"}
+
+	for _, v := range []struct {
+		ct     string
+		data   string
+		expect string
+	}{
+		{"application/json", `{"id":1,"name":"abc","password":"123"}`, `{"id":1,"password":"***"}`},
+		{"application/vnd.user+json", `{"id":1,"name":"abc","password":"123"}`, `{"name":"abc"}`},
+	} {
+		data, ok := redactbody(c, []byte(v.data), v.ct)
+		if !ok || string(data) != v.expect {
+			t.Errorf("%s: expect '%s', but got '%s' and %v", v.ct, v.expect, data, ok)
+		}
+	}
+}
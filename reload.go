@@ -0,0 +1,191 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/xgfone/gconf/v6"
+)
+
+var (
+	changelock      sync.Mutex
+	changecallbacks []func(old, new Config)
+)
+
+// OnConfigChange appends the callback, which is called with the old and new
+// effective configuration after the configuration is reloaded
+// by LoadConfigFile or WatchConfigFile, or updated by AdminHandler.
+func OnConfigChange(callback func(old, new Config)) {
+	changelock.Lock()
+	changecallbacks = append(changecallbacks, callback)
+	changelock.Unlock()
+}
+
+func notifyconfigchange(old, new Config) {
+	changelock.Lock()
+	callbacks := changecallbacks
+	changelock.Unlock()
+
+	for _, callback := range callbacks {
+		callback(old, new)
+	}
+}
+
+// LoadConfigFile loads the options from the file, whose keys are the option
+// names without the group prefix, such as {"reqbody": true, "bodymaxlen": 4096},
+// and the special key "ignorepaths" replaces the ignored paths.
+//
+// The format is decided by the file extension by the decoder registered
+// into gconf.Conf, which has "json" by default. So, for YAML, register
+// the "yaml" decoder by gconf.AddDecoder.
+//
+// All the options are validated before being updated together,
+// and the absent options are not changed.
+func LoadConfigFile(path string) (err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	format := strings.TrimPrefix(filepath.Ext(path), ".")
+	decoder := gconf.Conf.GetDecoder(format)
+	if decoder == nil {
+		return fmt.Errorf("no decoder for the config file format '%s'", format)
+	}
+
+	options := make(map[string]interface{}, 16)
+	if err = decoder(data, options); err != nil {
+		return fmt.Errorf("fail to decode the config file '%s': %w", path, err)
+	}
+
+	// Parse and validate all the options before updating them.
+	values := make(map[string]interface{}, len(options))
+	for name, value := range options {
+		if name == "ignorepaths" {
+			values[name] = value
+			continue
+		} else if !group.HasOpt(name) {
+			return fmt.Errorf("no option named '%s'", name)
+		}
+
+		if values[name], err = gconf.Conf.Parse(group.Prefix()+name, value); err != nil {
+			return
+		}
+	}
+
+	old := loadconfig().clone()
+	new := old.clone()
+	if err = decodeconfig(values, &new); err != nil {
+		return
+	} else if err = new.Validate(); err != nil {
+		return
+	}
+
+	loads := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		if name != "ignorepaths" {
+			loads[group.Prefix()+name] = value
+		}
+	}
+	if err = gconf.Conf.LoadMap(loads, true); err != nil {
+		return
+	}
+	if _, ok := values["ignorepaths"]; ok {
+		setignorepaths(new.IgnorePaths)
+	}
+
+	notifyconfigchange(old, EffectiveConfig())
+	return
+}
+
+// decodeconfig decodes the parsed option values into c by the json tags,
+// which are the same as the option names.
+func decodeconfig(values map[string]interface{}, c *Config) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, c)
+}
+
+// WatchConfigFile loads the config file by LoadConfigFile, then reloads it
+// when its modification time is changed, which is checked every interval,
+// or the process receives SIGHUP, until ctx is done.
+//
+// If interval is not positive, only reload it on SIGHUP.
+// The reloading error is logged by slog, and the options are not changed.
+func WatchConfigFile(ctx context.Context, path string, interval time.Duration) error {
+	if err := LoadConfigFile(path); err != nil {
+		return err
+	}
+	modtime := getmodtime(path)
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+
+	reload := func() {
+		modtime = getmodtime(path)
+		if err := LoadConfigFile(path); err != nil {
+			slog.Error("fail to reload the logger config file", "file", path, "err", err)
+		}
+	}
+
+	go func() {
+		defer signal.Stop(sighup)
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sighup:
+				reload()
+			case <-tick:
+				if !getmodtime(path).Equal(modtime) {
+					reload()
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func getmodtime(path string) time.Time {
+	if fi, err := os.Stat(path); err == nil {
+		return fi.ModTime()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	defer setignorepaths(loadignorepaths())
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logBodyMaxLen.Set(2048)
+		_ = logSlowThreshold.Set(time.Duration(0))
+		changelock.Lock()
+		changecallbacks = nil
+		changelock.Unlock()
+	}()
+
+	var lock sync.Mutex
+	var changes []Config
+	OnConfigChange(func(old, new Config) {
+		lock.Lock()
+		changes = append(changes, new)
+		lock.Unlock()
+	})
+
+	path := filepath.Join(t.TempDir(), "logger.json")
+	writefile := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writefile(`{"reqbody": true, "bodymaxlen": 16, "slowthreshold": "1s", "ignorepaths": ["/health"]}`)
+	if err := LoadConfigFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if !logReqBody.Get() || logBodyMaxLen.Get() != 16 || logSlowThreshold.Get() != time.Second {
+		t.Error("the options are not loaded")
+	}
+	if paths := loadignorepaths(); !slices.Equal(paths, []string{"/health"}) {
+		t.Errorf("unexpected ignore paths %v", paths)
+	}
+	if len(changes) != 1 || !changes[0].LogReqBody {
+		t.Errorf("unexpected changes %v", changes)
+	}
+
+	for _, data := range []string{
+		`{"reqbody": false, "bodymaxlen": -1}`,
+		`{"reqbody": false, "nooption": 1}`,
+		`{"reqbody": false,`,
+	} {
+		writefile(data)
+		if err := LoadConfigFile(path); err == nil {
+			t.Errorf("expect an error for '%s'", data)
+		}
+	}
+	if !logReqBody.Get() || len(changes) != 1 {
+		t.Error("the options are changed by the invalid config file")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	writefile(`{"bodymaxlen": 32}`)
+	if err := WatchConfigFile(ctx, path, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	writefile(`{"bodymaxlen": 64}`)
+	modtime := time.Now().Add(time.Second)
+	_ = os.Chtimes(path, modtime, modtime)
+	for i := 0; i < 100 && logBodyMaxLen.Get() != 64; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if v := logBodyMaxLen.Get(); v != 64 {
+		t.Errorf("expect bodymaxlen %d, but got %d", 64, v)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+type serviceprefix struct {
+	prefix  string
+	service string
+}
+
+var servicemapping atomic.Pointer[[]serviceprefix]
+
+// SetServiceMapping sets the mapping from the path prefix to the service name,
+// so that Collect appends the attribute service by the longest matched prefix.
+// The unmapped paths use the option defaultservice as the service name.
+//
+// The trailing "*" of the prefix is ignored, so "/billing/*" is equal to
+// "/billing/". If mapping is empty, the attribute service is not appended.
+func SetServiceMapping(mapping map[string]string) {
+	prefixes := make([]serviceprefix, 0, len(mapping))
+	for prefix, service := range mapping {
+		prefix = strings.TrimSuffix(prefix, "*")
+		prefixes = append(prefixes, serviceprefix{prefix: prefix, service: service})
+	}
+
+	// Sort by the prefix length in descending order for the longest matching.
+	slices.SortFunc(prefixes, func(a, b serviceprefix) int {
+		if n := len(b.prefix) - len(a.prefix); n != 0 {
+			return n
+		}
+		return strings.Compare(a.prefix, b.prefix)
+	})
+	servicemapping.Store(&prefixes)
+}
+
+// getservice returns the service name of the path.
+//
+// If no service mapping is set, ok is false.
+func getservice(c *Config, path string) (service string, ok bool) {
+	prefixes := servicemapping.Load()
+	if prefixes == nil || len(*prefixes) == 0 {
+		return
+	}
+
+	for _, p := range *prefixes {
+		if strings.HasPrefix(path, p.prefix) {
+			return p.service, true
+		}
+	}
+	return c.DefaultService, true
+}
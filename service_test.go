@@ -0,0 +1,42 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServiceMapping(t *testing.T) {
+	SetServiceMapping(map[string]string{
+		"/billing/*":        "billing",
+		"/billing/invoice/": "invoice",
+		"/users/":           "users",
+	})
+	defer SetServiceMapping(nil)
+
+	for path, service := range map[string]string{
+		"/billing/pay":         "billing",
+		"/billing/invoice/123": "invoice",
+		"/users/1":             "users",
+		"/other":               "default",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if v := collectAttrs(httptest.NewRecorder(), req)["service"].String(); v != service {
+			t.Errorf("%s: expect service '%s', but got '%s'", path, service, v)
+		}
+	}
+}
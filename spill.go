@@ -0,0 +1,166 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+const spillpattern = "loggerext-*.body"
+
+var spillcleaned atomic.Int64 // The unix nanoseconds of the last cleanup.
+
+// spillfile streams the body larger than bodymaxlen into a temp file
+// in the directory configured by the option spilldir.
+//
+// The file is created lazily only when the body overflows.
+type spillfile struct {
+	config     *Config
+	bodymaxlen int
+	dir        string
+	maxlen     int // The maximum length of the spilled body. 0 means no limit.
+	retention  time.Duration
+
+	file      *os.File
+	size      int
+	hash      hash.Hash
+	truncated bool
+	failed    bool
+}
+
+func newspillfile(c *Config) *spillfile {
+	if c.SpillDir == "" || c.BodyMaxLen <= 0 {
+		return nil
+	}
+	return &spillfile{
+		config:     c,
+		bodymaxlen: c.BodyMaxLen,
+		dir:        c.SpillDir,
+		maxlen:     c.SpillMaxLen,
+		retention:  c.SpillRetention,
+	}
+}
+
+// forct returns nil if the body with the content type ct is redacted
+// or filtered by ctbodyfields before logging, which must not be spilled as-is.
+func (s *spillfile) forct(ct string) *spillfile {
+	if s == nil || s.config.forct(ct).redactsbody(ct) {
+		return nil
+	}
+	return s
+}
+
+// redactsbody reports whether the body with the content type ct
+// is redacted or scrubbed before logging.
+func (c *Config) redactsbody(ct string) bool {
+	_, decoded := ctdecoders[ct]
+	switch {
+	case len(c.ScrubPII) > 0:
+		return true
+	case isjsonct(ct) || decoded:
+		return len(c.RedactFields) > 0 || len(c.BodyFields) > 0
+	case isxmlct(ct):
+		return len(c.RedactXML) > 0
+	case isformct(ct):
+		return c.FormBody && len(c.RedactQueries) > 0
+	default:
+		return false
+	}
+}
+
+// write writes p into the file, and writes head first when creating the file.
+func (s *spillfile) write(head, p []byte) {
+	if s.failed || s.truncated {
+		return
+	}
+
+	if s.file == nil {
+		cleanspill(s.dir, s.retention)
+
+		file, err := os.CreateTemp(s.dir, spillpattern)
+		if err != nil {
+			s.failed = true
+			slog.Error("fail to create the spill file", "dir", s.dir, "err", err)
+			return
+		}
+
+		s.file, s.hash = file, sha256.New()
+		s.write(nil, head)
+	}
+
+	if s.maxlen > 0 && s.size+len(p) > s.maxlen {
+		p, s.truncated = p[:s.maxlen-s.size], true
+	}
+
+	n, err := s.file.Write(p)
+	s.hash.Write(p[:n])
+	s.size += n
+	if err != nil {
+		s.failed = true
+		slog.Error("fail to write the spill file", "file", s.file.Name(), "err", err)
+	}
+}
+
+// appendattrs appends the path, size and SHA-256 hash of the spilled body.
+//
+// The hash is only appended when the whole body has been spilled.
+func (s *spillfile) appendattrs(appendAttr func(...slog.Attr), key string, whole bool) {
+	if s == nil || s.file == nil {
+		return
+	}
+
+	appendAttr(slog.String(key+"file", s.file.Name()), slog.Int(key+"filelen", s.size))
+	if s.truncated || s.failed {
+		appendAttr(slog.Bool(key+"filetruncated", true))
+	} else if whole {
+		appendAttr(slog.String(key+"filesha256", hex.EncodeToString(s.hash.Sum(nil))))
+	}
+}
+
+func (s *spillfile) close() {
+	if s != nil && s.file != nil {
+		_ = s.file.Close()
+	}
+}
+
+// cleanspill removes the expired spill files in the background,
+// which is run at most once per minute.
+func cleanspill(dir string, retention time.Duration) {
+	now := time.Now()
+	last := spillcleaned.Load()
+	if retention <= 0 || now.UnixNano()-last < int64(time.Minute) ||
+		!spillcleaned.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+
+	go removespill(dir, now.Add(-retention))
+}
+
+// removespill removes the spill files modified before the given time.
+func removespill(dir string, before time.Time) {
+	files, _ := filepath.Glob(filepath.Join(dir, spillpattern))
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil && info.ModTime().Before(before) {
+			_ = os.Remove(file)
+		}
+	}
+}
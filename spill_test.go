@@ -0,0 +1,174 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpillBody(t *testing.T) {
+	dir := t.TempDir()
+	_ = logSpillDir.Set(dir)
+	_ = logBodyMaxLen.Set(8)
+	_ = logReqBody.Set(true)
+	_ = logRespBody.Set(true)
+	defer func() {
+		_ = logSpillDir.Set("")
+		_ = logBodyMaxLen.Set(optdefault[int](logBodyMaxLen))
+		_ = logReqBody.Set(false)
+		_ = logRespBody.Set(false)
+	}()
+
+	reqdata := strings.Repeat("a", 100)
+	respdata := strings.Repeat("b", 50)
+
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(reqdata))
+	req.Header.Set("Content-Type", "text/plain")
+	w, r := WrapReqRespBody(httptest.NewRecorder(), req)
+	if data, _ := io.ReadAll(r.Body); string(data) != reqdata {
+		t.Errorf("unexpected request body '%s'", data)
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	for i := 0; i < 5; i++ {
+		_, _ = io.WriteString(w, respdata[:10])
+	}
+	attrs := collectAttrs(w, r)
+	Release(w, r)
+
+	for key, expect := range map[string]string{"reqbody": reqdata, "respbody": respdata} {
+		if _, ok := attrs[key]; ok {
+			t.Errorf("unexpected %s: %v", key, attrs[key])
+		}
+
+		file := attrs[key+"file"].String()
+		if filepath.Dir(file) != dir {
+			t.Errorf("unexpected %sfile '%s'", key, file)
+		}
+		if data, err := os.ReadFile(file); err != nil {
+			t.Error(err)
+		} else if string(data) != expect {
+			t.Errorf("expect %s '%s', but got '%s'", key, expect, data)
+		}
+
+		if size := attrs[key+"filelen"].Int64(); size != int64(len(expect)) {
+			t.Errorf("expect %sfilelen %d, but got %d", key, len(expect), size)
+		}
+
+		sum := sha256.Sum256([]byte(expect))
+		if v := attrs[key+"filesha256"].String(); v != hex.EncodeToString(sum[:]) {
+			t.Errorf("unexpected %sfilesha256 '%s'", key, v)
+		}
+	}
+
+	// The small body is not spilled.
+	req = httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("abc"))
+	req.Header.Set("Content-Type", "text/plain")
+	attrs = serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+	if _, ok := attrs["reqbodyfile"]; ok || attrs["reqbody"].String() != "abc" {
+		t.Errorf("unexpected attrs: %v", attrs)
+	}
+}
+
+func TestSpillRedactedBody(t *testing.T) {
+	_ = logSpillDir.Set(t.TempDir())
+	_ = logBodyMaxLen.Set(8)
+	_ = logReqBody.Set(true)
+	_ = logRespBody.Set(true)
+	_ = logRedactFields.Set([]string{"password"})
+	defer func() {
+		_ = logSpillDir.Set("")
+		_ = logBodyMaxLen.Set(optdefault[int](logBodyMaxLen))
+		_ = logReqBody.Set(false)
+		_ = logRespBody.Set(false)
+		_ = logRedactFields.Set([]string{})
+	}()
+
+	data := `{"name":"abc","password":"123456"}`
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, data)
+	}, req)
+
+	for _, key := range []string{"reqbodyfile", "respbodyfile"} {
+		if v, ok := attrs[key]; ok {
+			t.Errorf("unexpected %s '%s'", key, v)
+		}
+	}
+}
+
+func TestRemoveSpill(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "loggerext-old.body")
+	cur := filepath.Join(dir, "loggerext-cur.body")
+	other := filepath.Join(dir, "other.body")
+	for _, file := range []string{old, cur, other} {
+		if err := os.WriteFile(file, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	past := time.Now().Add(-2 * time.Hour)
+	_ = os.Chtimes(old, past, past)
+	_ = os.Chtimes(other, past, past)
+	removespill(dir, time.Now().Add(-time.Hour))
+
+	for file, exist := range map[string]bool{old: false, cur: true, other: true} {
+		if _, err := os.Stat(file); (err == nil) != exist {
+			t.Errorf("%s: expect exist=%v, but got err=%v", file, exist, err)
+		}
+	}
+}
+
+func TestSpillCTBodyFields(t *testing.T) {
+	_ = logSpillDir.Set(t.TempDir())
+	_ = logBodyMaxLen.Set(8)
+	_ = logReqBody.Set(true)
+	_ = logRespBody.Set(true)
+	_ = logCTBodyFields.Set([]string{"application/json=name"})
+	defer func() {
+		_ = logSpillDir.Set("")
+		_ = logBodyMaxLen.Set(optdefault[int](logBodyMaxLen))
+		_ = logReqBody.Set(false)
+		_ = logRespBody.Set(false)
+		_ = logCTBodyFields.Set([]string{})
+	}()
+
+	data := `{"name":"abc","password":"123456"}`
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, data)
+	}, req)
+
+	for _, key := range []string{"reqbodyfile", "respbodyfile"} {
+		if v, ok := attrs[key]; ok {
+			t.Errorf("unexpected %s '%s'", key, v)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"net/http"
+	"strings"
+)
+
+// acceptseventstream reports whether the request accepts the server-sent events.
+func acceptseventstream(header http.Header) bool {
+	for _, accept := range header.Values("Accept") {
+		if strings.Contains(accept, "text/event-stream") {
+			return true
+		}
+	}
+	return false
+}
+
+// iseventstream reports whether the response is the server-sent events.
+func iseventstream(header http.Header) bool {
+	return getContentType(header) == "text/event-stream"
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventStream(t *testing.T) {
+	_ = logRespBody.Set(true)
+	defer func() { _ = logRespBody.Set(false) }()
+
+	var flushed bool
+	req := httptest.NewRequest(http.MethodGet, "/path", nil)
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		_, _ = w.Write([]byte("data: abc\n\n"))
+		w.(http.Flusher).Flush()
+
+		rw := getResponseWriter(w)
+		flushed = rw.ResponseWriter.(*httptest.ResponseRecorder).Flushed
+		if rw.buf != nil {
+			t.Errorf("unexpected the buffered event stream")
+		}
+	}, req)
+
+	if !flushed {
+		t.Errorf("expect the event stream to be flushed")
+	}
+	if v := attrs["respbody_skipped"].String(); v != "event_stream" {
+		t.Errorf("expect respbody_skipped '%s', but got '%s'", "event_stream", v)
+	}
+	if v, ok := attrs["respbody"]; ok {
+		t.Errorf("unexpected respbody '%v'", v)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/path", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	w, r := WrapReqRespBody(httptest.NewRecorder(), req)
+	defer Release(w, r)
+	if getResponseWriter(w) != nil {
+		t.Errorf("unexpected the wrapped response writer for the event stream request")
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"slices"
+	"sync"
+)
+
+// SizePercentiles is the percentiles of the body sizes in the window.
+type SizePercentiles struct {
+	Count int `json:"count"`
+	P50   int `json:"p50"`
+	P95   int `json:"p95"`
+	P99   int `json:"p99"`
+}
+
+// SizeStats is the statistics of the request and response body sizes.
+type SizeStats struct {
+	Request  SizePercentiles `json:"request"`
+	Response SizePercentiles `json:"response"`
+}
+
+var reqsizes, respsizes sizeWindow
+
+// BodySizeStats returns the percentiles of the request and response
+// body sizes in the rolling window, whose size is configured by
+// the option sizewindow, which is disabled by default.
+func BodySizeStats() SizeStats {
+	return SizeStats{Request: reqsizes.percentiles(), Response: respsizes.percentiles()}
+}
+
+// sizeWindow is a ring buffer of the latest body sizes.
+type sizeWindow struct {
+	lock  sync.Mutex
+	sizes []int
+	next  int
+}
+
+func (w *sizeWindow) add(size int) {
+	window := logSizeWindow.Get()
+	if window <= 0 {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if len(w.sizes) != window && w.next > 0 {
+		// The window is resized, so reorder the sizes from the oldest.
+		w.sizes = append(slices.Clone(w.sizes[w.next:]), w.sizes[:w.next]...)
+		w.next = 0
+	}
+
+	if len(w.sizes) < window {
+		w.sizes = append(w.sizes, size)
+		return
+	}
+
+	if len(w.sizes) > window { // The window is shrunk, so keep the latest.
+		w.sizes = slices.Clone(w.sizes[len(w.sizes)-window:])
+	}
+
+	w.sizes[w.next] = size
+	w.next = (w.next + 1) % window
+}
+
+func (w *sizeWindow) percentiles() (p SizePercentiles) {
+	w.lock.Lock()
+	sizes := slices.Clone(w.sizes)
+	w.lock.Unlock()
+
+	if p.Count = len(sizes); p.Count == 0 {
+		return
+	}
+
+	slices.Sort(sizes)
+	p.P50 = percentile(sizes, 50)
+	p.P95 = percentile(sizes, 95)
+	p.P99 = percentile(sizes, 99)
+	return
+}
+
+// percentile returns the p-th percentile of the sorted sizes
+// by the nearest-rank method.
+func percentile(sizes []int, p int) int {
+	rank := (p*len(sizes) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sizes[rank-1]
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBodySizeStats(t *testing.T) {
+	_ = logSizeWindow.Set(100)
+	defer func() { _ = logSizeWindow.Set(0) }()
+
+	for i := 1; i <= 200; i++ {
+		reqsizes.add(i) // Only the last 100 sizes, that's, 101~200, are kept.
+	}
+
+	stats := BodySizeStats().Request
+	if stats.Count != 100 {
+		t.Errorf("expect count %d, but got %d", 100, stats.Count)
+	}
+	if stats.P50 != 150 {
+		t.Errorf("expect p50 %d, but got %d", 150, stats.P50)
+	}
+	if stats.P95 != 195 {
+		t.Errorf("expect p95 %d, but got %d", 195, stats.P95)
+	}
+	if stats.P99 != 199 {
+		t.Errorf("expect p99 %d, but got %d", 199, stats.P99)
+	}
+}
+
+func TestSizeWindowShrink(t *testing.T) {
+	_ = logSizeWindow.Set(4)
+	defer func() { _ = logSizeWindow.Set(0) }()
+
+	var w sizeWindow
+	for size := 1; size <= 6; size++ {
+		w.add(size) // [5, 6, 3, 4]
+	}
+
+	_ = logSizeWindow.Set(2)
+	w.add(7)
+	if sizes := w.sizes; !slices.Equal(sizes, []int{7, 6}) {
+		t.Errorf("expect the latest sizes %v, but got %v", []int{7, 6}, sizes)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+)
+
+// appendtlsattrs appends the attributes about the TLS connection.
+func appendtlsattrs(c *Config, r *http.Request, appendAttr func(...slog.Attr)) {
+	if r.TLS == nil {
+		return
+	}
+
+	if c.LogTLS {
+		appendAttr(
+			slog.String("tlsversion", tls.VersionName(r.TLS.Version)),
+			slog.String("tlscipher", tls.CipherSuiteName(r.TLS.CipherSuite)),
+		)
+		if r.TLS.ServerName != "" {
+			appendAttr(slog.String("tlsservername", r.TLS.ServerName))
+		}
+		if r.TLS.NegotiatedProtocol != "" {
+			appendAttr(slog.String("tlsprotocol", r.TLS.NegotiatedProtocol))
+		}
+	}
+
+	if !c.LogClientCert || len(r.TLS.PeerCertificates) == 0 {
+		return
+	}
+
+	cert := r.TLS.PeerCertificates[0]
+	appendAttr(slog.String("clientcert", cert.Subject.CommonName))
+	if c.LogClientCertDetail {
+		sum := sha256.Sum256(cert.Raw)
+		appendAttr(
+			slog.String("clientcertsubject", cert.Subject.String()),
+			slog.String("clientcertserial", cert.SerialNumber.String()),
+			slog.String("clientcertsha256", hex.EncodeToString(sum[:])),
+		)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestCert(t *testing.T, cn string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(123),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestClientCert(t *testing.T) {
+	_ = logClientCert.Set(true)
+	_ = logClientCertDetail.Set(true)
+	defer func() {
+		_ = logClientCert.Set(false)
+		_ = logClientCertDetail.Set(false)
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/path", nil)
+	if _, ok := collectAttrs(httptest.NewRecorder(), req)["clientcert"]; ok {
+		t.Error("unexpect attr clientcert without TLS")
+	}
+
+	req.TLS = &tls.ConnectionState{}
+	if _, ok := collectAttrs(httptest.NewRecorder(), req)["clientcert"]; ok {
+		t.Error("unexpect attr clientcert without peer certificates")
+	}
+
+	cert := newTestCert(t, "client1")
+	req.TLS.PeerCertificates = []*x509.Certificate{cert}
+	attrs := collectAttrs(httptest.NewRecorder(), req)
+	if v := attrs["clientcert"].String(); v != "client1" {
+		t.Errorf("expect clientcert '%s', but got '%s'", "client1", v)
+	}
+	if v := attrs["clientcertsubject"].String(); v != "CN=client1" {
+		t.Errorf("expect clientcertsubject '%s', but got '%s'", "CN=client1", v)
+	}
+	if v := attrs["clientcertserial"].String(); v != "123" {
+		t.Errorf("expect clientcertserial '%s', but got '%s'", "123", v)
+	}
+
+	sum := sha256.Sum256(cert.Raw)
+	if v := attrs["clientcertsha256"].String(); v != hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected clientcertsha256 '%s'", v)
+	}
+}
+
+func TestTLSAttrs(t *testing.T) {
+	_ = logTLS.Set(true)
+	defer func() { _ = logTLS.Set(false) }()
+
+	req := httptest.NewRequest(http.MethodGet, "/path", nil)
+	if _, ok := collectAttrs(httptest.NewRecorder(), req)["tlsversion"]; ok {
+		t.Error("unexpect attr tlsversion without TLS")
+	}
+
+	req.TLS = &tls.ConnectionState{
+		Version:            tls.VersionTLS13,
+		CipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+		ServerName:         "example.com",
+		NegotiatedProtocol: "h2",
+	}
+	attrs := collectAttrs(httptest.NewRecorder(), req)
+	for key, expect := range map[string]string{
+		"tlsversion":    "TLS 1.3",
+		"tlscipher":     "TLS_AES_128_GCM_SHA256",
+		"tlsservername": "example.com",
+		"tlsprotocol":   "h2",
+	} {
+		if v := attrs[key].String(); v != expect {
+			t.Errorf("expect %s '%s', but got '%s'", key, expect, v)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// appendtrailers appends the request trailers, which are only available
+// after the handler reads the whole body, and the response trailers.
+func appendtrailers(c *Config, w http.ResponseWriter, r *http.Request, appendAttr func(...slog.Attr)) {
+	if trailer := gettrailer(r.Trailer); len(trailer) > 0 {
+		appendAttr(slog.Any("reqtrailers", redactheaders(c, trailer)))
+	}
+
+	if trailer := getresptrailer(w.Header()); len(trailer) > 0 {
+		appendAttr(slog.Any("resptrailers", redactheaders(c, trailer)))
+	}
+}
+
+// gettrailer returns the trailers whose values have been received.
+func gettrailer(trailer http.Header) http.Header {
+	var _trailer http.Header
+	for name, values := range trailer {
+		if len(values) > 0 {
+			if _trailer == nil {
+				_trailer = make(http.Header, len(trailer))
+			}
+			_trailer[name] = values
+		}
+	}
+	return _trailer
+}
+
+// getresptrailer returns the response trailers, which are declared
+// by the header Trailer or set with the prefix http.TrailerPrefix.
+func getresptrailer(header http.Header) http.Header {
+	var trailer http.Header
+	add := func(name string, values []string) {
+		if len(values) > 0 {
+			if trailer == nil {
+				trailer = make(http.Header)
+			}
+			trailer[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	for _, names := range header.Values("Trailer") {
+		for _, name := range strings.Split(names, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				add(name, header.Values(name))
+			}
+		}
+	}
+
+	for name, values := range header {
+		if strings.HasPrefix(name, http.TrailerPrefix) {
+			add(strings.TrimPrefix(name, http.TrailerPrefix), values)
+		}
+	}
+
+	return trailer
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrailers(t *testing.T) {
+	_ = logTrailers.Set(true)
+	defer func() { _ = logTrailers.Set(false) }()
+
+	handler := new(recordHandler)
+	server := httptest.NewServer(Middleware(slog.New(handler))(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.ReadAll(r.Body)
+			w.Header().Set("Trailer", "Grpc-Status")
+			_, _ = w.Write([]byte("abc"))
+			w.Header().Set("Grpc-Status", "0")
+			w.Header().Set(http.TrailerPrefix+"Grpc-Message", "ok")
+		})))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/path", nil)
+	req.ContentLength = -1
+	req.Trailer = http.Header{"Checksum": nil, "Authorization": nil}
+	req.Body = io.NopCloser(&trailerReader{Reader: strings.NewReader("xyz"), req: req})
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if v := resp.Trailer.Get("Grpc-Status"); v != "0" {
+		t.Errorf("expect the response trailer Grpc-Status '0', but got '%s'", v)
+	}
+
+	// The access log is emitted after the response is sent.
+	var attrs map[string]slog.Value
+	for i := 0; i < 100 && attrs == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		attrs = handler.last()
+	}
+	if attrs == nil {
+		t.Fatal("expect a log record, but got nil")
+	}
+
+	reqtrailer, _ := attrs["reqtrailers"].Any().(http.Header)
+	if v := reqtrailer.Get("Checksum"); v != "123" {
+		t.Errorf("expect the request trailer Checksum '123', but got '%s'", v)
+	}
+	if v := reqtrailer.Get("Authorization"); v != RedactedValue {
+		t.Errorf("expect the request trailer Authorization '%s', but got '%s'", RedactedValue, v)
+	}
+
+	resptrailer, _ := attrs["resptrailers"].Any().(http.Header)
+	if v := resptrailer.Get("Grpc-Status"); v != "0" {
+		t.Errorf("expect the response trailer Grpc-Status '0', but got '%s'", v)
+	}
+	if v := resptrailer.Get("Grpc-Message"); v != "ok" {
+		t.Errorf("expect the response trailer Grpc-Message 'ok', but got '%s'", v)
+	}
+}
+
+// trailerReader sets the request trailers when reaching EOF.
+type trailerReader struct {
+	io.Reader
+	req *http.Request
+}
+
+func (r *trailerReader) Read(p []byte) (n int, err error) {
+	if n, err = r.Reader.Read(p); err == io.EOF {
+		r.req.Trailer.Set("Checksum", "123")
+		r.req.Trailer.Set("Authorization", "secret")
+	}
+	return
+}
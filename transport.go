@@ -0,0 +1,323 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Options is used to configure the outbound logging transport.
+type Options struct {
+	// Logger is used to emit the log record of each outbound call.
+	//
+	// Default: slog.Default()
+	Logger *slog.Logger
+
+	// Level is the level of the log record of the successful call.
+	// The failed call is always logged with slog.LevelError.
+	//
+	// Default: slog.LevelInfo
+	Level slog.Level
+}
+
+// NewLoggingTransport returns a new http.RoundTripper, which wraps base
+// and logs each outbound call with the same attributes as Collect,
+// such as the request and response headers and bodies.
+//
+// The log record is emitted with the context of the request when the response
+// body is read to EOF or closed, or the call fails. So the caller must close
+// the response body as usual. And the slog handler can extract the trace
+// or request id from the context like the inbound side.
+//
+// If base is nil, use http.DefaultTransport instead.
+func NewLoggingTransport(base http.RoundTripper, opts Options) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return &loggingTransport{base: base, opts: opts}
+}
+
+// NewRoundTripper is equal to NewLoggingTransport(next, Options{}).
+func NewRoundTripper(next http.RoundTripper) http.RoundTripper {
+	return NewLoggingTransport(next, Options{})
+}
+
+type loggingTransport struct {
+	base http.RoundTripper
+	opts Options
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	start := time.Now()
+	c := getconfig(req)
+	req, reqbody := t.wrapRequestBody(c, req)
+
+	resp, err = t.base.RoundTrip(req)
+	if err != nil {
+		t.log(c, req, nil, reqbody, nil, start, time.Since(start), err)
+		reqbody.release()
+		return
+	}
+
+	duration := time.Since(start)
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		// The body is the underlying connection, which must not be wrapped.
+		t.log(c, req, resp, reqbody, nil, start, duration, nil)
+		reqbody.release()
+		return
+	}
+
+	respbody := &transportBody{
+		ReadCloser: resp.Body,
+		transport:  t,
+		config:     c,
+		request:    req,
+		response:   resp,
+		reqbody:    reqbody,
+		start:      start,
+		duration:   duration,
+	}
+
+	if ct := getContentType(resp.Header); c.LogRespBody && containsct(c, ct) {
+		respbody.capture = newCaptureBuffer(c, ct)
+	}
+
+	resp.Body = respbody
+	return
+}
+
+func (t *loggingTransport) wrapRequestBody(c *Config, req *http.Request) (*http.Request, *captureBuffer) {
+	if !c.LogReqBody || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+
+	ct := getContentType(req.Header)
+	if !containsct(c, ct) {
+		return req, nil
+	}
+
+	reqbody := newCaptureBuffer(c, ct)
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			_, _ = io.Copy(reqbody, body)
+			_ = body.Close()
+			return req, reqbody
+		}
+	}
+
+	// Capture the body when the transport reads it.
+	newreq := new(http.Request)
+	*newreq = *req
+	newreq.Body = &captureReader{ReadCloser: req.Body, capture: reqbody}
+	return newreq, reqbody
+}
+
+func (t *loggingTransport) log(c *Config, req *http.Request, resp *http.Response,
+	reqbody, respbody *captureBuffer, start time.Time, duration time.Duration, err error) {
+	attrs := make([]slog.Attr, 0, 16)
+	appendAttr := c.Keys.wrap(func(as ...slog.Attr) { attrs = append(attrs, as...) })
+
+	u := *req.URL
+	u.RawQuery, u.ForceQuery = "", false
+	appendAttr(
+		slog.String("direction", "outbound"),
+		slog.String("method", req.Method),
+		slog.String("url", u.String()),
+	)
+
+	if c.LogQuery {
+		appendAttr(slog.String("query", redactquery(c, req.URL.RawQuery)))
+	}
+
+	if c.LogReqHeaders {
+		appendAttr(slog.Any("reqheaders", redactheaders(c, req.Header)))
+	}
+
+	if reqbody != nil {
+		data, size := reqbody.snapshot()
+		appendbodyattrs(c, appendAttr, req, "request", reqbody.ct, data, size)
+	}
+
+	level := t.opts.Level
+	if err != nil {
+		level = slog.LevelError
+		appendAttr(slog.Duration("duration", duration), slog.String("err", err.Error()))
+	} else {
+		appendAttr(
+			slog.Int("status", resp.StatusCode),
+			slog.Duration("duration", duration),
+			slog.Duration("totalduration", time.Since(start)),
+		)
+
+		if c.LogRespHeaders {
+			appendAttr(slog.Any("respheaders", redactheaders(c, resp.Header)))
+		}
+	}
+
+	if respbody != nil {
+		data, size := respbody.snapshot()
+		appendbodyattrs(c, appendAttr, req, "response", respbody.ct, data, size)
+		if respbody.partial {
+			appendAttr(slog.Bool("respbodypartial", true))
+		}
+	}
+
+	t.opts.Logger.LogAttrs(req.Context(), level, "outbound http request", attrs...)
+}
+
+// transportBody is the response body of the outbound call,
+// which emits the log record when it is read to EOF or closed.
+type transportBody struct {
+	io.ReadCloser
+
+	transport *loggingTransport
+	config    *Config
+	request   *http.Request
+	response  *http.Response
+	reqbody   *captureBuffer
+	capture   *captureBuffer // nil if the body is not captured
+	start     time.Time
+	duration  time.Duration
+
+	size int
+	once sync.Once
+}
+
+func (b *transportBody) Read(p []byte) (n int, err error) {
+	n, err = b.ReadCloser.Read(p)
+	if n > 0 {
+		b.size += n
+		if b.capture != nil {
+			_, _ = b.capture.Write(p[:n])
+		}
+	}
+
+	if err != nil {
+		b.finish(err != io.EOF)
+	}
+	return
+}
+
+func (b *transportBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish(true)
+	return err
+}
+
+func (b *transportBody) finish(partial bool) {
+	b.once.Do(func() {
+		respbody := b.capture
+		if respbody == nil && b.config.LogRespBody {
+			// Only log the length of the body which is not captured.
+			respbody = &captureBuffer{}
+		}
+		if respbody != nil {
+			respbody.size, respbody.partial = b.size, partial
+		}
+
+		b.transport.log(b.config, b.request, b.response, b.reqbody, respbody, b.start, b.duration, nil)
+		b.reqbody.release()
+		b.capture.release()
+	})
+}
+
+// captureReader captures the data read from the request body.
+type captureReader struct {
+	io.ReadCloser
+	capture *captureBuffer
+}
+
+func (r *captureReader) Read(p []byte) (n int, err error) {
+	if n, err = r.ReadCloser.Read(p); n > 0 {
+		_, _ = r.capture.Write(p[:n])
+	}
+	return
+}
+
+// captureBuffer buffers at most bodymaxlen+1 bytes of the body,
+// and counts the total length of the written data.
+//
+// It may be written by the transport in the other goroutine,
+// so the access is protected by the mutex.
+type captureBuffer struct {
+	lock    sync.Mutex
+	buf     *bytes.Buffer
+	limit   int
+	size    int
+	ct      string
+	partial bool
+}
+
+func newCaptureBuffer(c *Config, ct string) *captureBuffer {
+	var limit int
+	if maxlen := c.BodyMaxLen; maxlen > 0 {
+		limit = maxlen + 1
+	}
+	return &captureBuffer{buf: getbuffer(), limit: limit, ct: ct}
+}
+
+func (b *captureBuffer) Write(p []byte) (n int, err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	n = len(p)
+	b.size += n
+	if b.buf == nil {
+		return
+	}
+
+	if b.limit > 0 {
+		if left := b.limit - b.buf.Len(); left <= 0 {
+			return
+		} else if len(p) > left {
+			p = p[:left]
+		}
+	}
+	b.buf.Write(p)
+	return
+}
+
+func (b *captureBuffer) snapshot() (data []byte, size int) {
+	b.lock.Lock()
+	if b.buf != nil {
+		data = b.buf.Bytes()
+	}
+	size = b.size
+	b.lock.Unlock()
+	return
+}
+
+func (b *captureBuffer) release() {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	buf := b.buf
+	b.buf = nil
+	b.lock.Unlock()
+
+	if buf != nil {
+		putbuffer(buf)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordHandler struct {
+	lock    sync.Mutex
+	records []map[string]slog.Value
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler            { return h }
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := make(map[string]slog.Value, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		// Resolve the string to a copy since the body buffer will be released.
+		if a.Value.Kind() == slog.KindString {
+			a.Value = slog.StringValue(strings.Clone(a.Value.String()))
+		}
+		attrs[a.Key] = a.Value
+		return true
+	})
+
+	h.lock.Lock()
+	h.records = append(h.records, attrs)
+	h.lock.Unlock()
+	return nil
+}
+
+func (h *recordHandler) last() map[string]slog.Value {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if len(h.records) == 0 {
+		return nil
+	}
+	return h.records[len(h.records)-1]
+}
+
+func TestLoggingTransport(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logRespBody.Set(true)
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logRespBody.Set(false)
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		if r.URL.Path == "/large" {
+			_, _ = io.WriteString(w, strings.Repeat("a", 1000))
+		} else {
+			_, _ = io.Copy(w, r.Body)
+		}
+	}))
+	defer server.Close()
+
+	handler := new(recordHandler)
+	client := &http.Client{Transport: NewLoggingTransport(nil, Options{Logger: slog.New(handler)})}
+
+	// The request body with GetBody.
+	resp, err := client.Post(server.URL+"/echo", "text/plain", strings.NewReader("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	attrs := handler.last()
+	if v := attrs["direction"].String(); v != "outbound" {
+		t.Errorf("expect direction '%s', but got '%s'", "outbound", v)
+	}
+	if v := attrs["status"].Int64(); v != 200 {
+		t.Errorf("expect status %d, but got %d", 200, v)
+	}
+	if v := attrs["reqbody"].String(); v != "abc" {
+		t.Errorf("expect reqbody '%s', but got '%s'", "abc", v)
+	}
+	if v := attrs["respbody"].String(); v != "abc" {
+		t.Errorf("expect respbody '%s', but got '%s'", "abc", v)
+	}
+
+	// The request body without GetBody.
+	body := io.NopCloser(strings.NewReader("xyz"))
+	resp, err = client.Post(server.URL+"/echo", "text/plain", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	attrs = handler.last()
+	if v := attrs["reqbody"].String(); v != "xyz" {
+		t.Errorf("expect reqbody '%s', but got '%s'", "xyz", v)
+	}
+
+	// The response body is not read fully.
+	resp, err = client.Get(server.URL + "/large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.ReadFull(resp.Body, make([]byte, 10))
+	_ = resp.Body.Close()
+
+	attrs = handler.last()
+	if v := attrs["respbodylen"].Int64(); v != 10 {
+		t.Errorf("expect respbodylen %d, but got %d", 10, v)
+	}
+	if v := attrs["respbody"].String(); v != strings.Repeat("a", 10) {
+		t.Errorf("expect respbody '%s', but got '%s'", strings.Repeat("a", 10), v)
+	}
+	if !attrs["respbodypartial"].Bool() {
+		t.Errorf("expect respbodypartial, but got not")
+	}
+
+	handler.lock.Lock()
+	if n := len(handler.records); n != 3 {
+		t.Errorf("expect %d records, but got %d", 3, n)
+	}
+	handler.lock.Unlock()
+}
+
+func TestLoggingTransportRedactHeaders(t *testing.T) {
+	_ = logReqHeaders.Set(true)
+	defer func() { _ = logReqHeaders.Set(false) }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	handler := new(recordHandler)
+	transport := NewLoggingTransport(nil, Options{Logger: slog.New(handler)})
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Authorization", "Bearer token")
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	header, _ := handler.last()["reqheaders"].Any().(http.Header)
+	if v := header.Get("Authorization"); v != RedactedValue {
+		t.Errorf("expect Authorization '%s', but got '%s'", RedactedValue, v)
+	}
+	if v := req.Header.Get("Authorization"); v != "Bearer token" {
+		t.Errorf("the original header is modified: %s", v)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"strconv"
+)
+
+// tailbuf is a ring buffer to keep the last bytes of the body,
+// which is used by the option truncatetail.
+type tailbuf struct {
+	buf     []byte
+	pos     int
+	full    bool
+	written int
+}
+
+func newtailbuf(c *Config) *tailbuf {
+	if !c.TruncateBody || c.TruncateTail <= 0 || c.BodyMaxLen <= c.TruncateTail {
+		return nil
+	}
+	return &tailbuf{buf: make([]byte, c.TruncateTail)}
+}
+
+func (t *tailbuf) write(p []byte) {
+	if t == nil || len(p) == 0 {
+		return
+	}
+
+	t.written += len(p)
+	if len(p) >= len(t.buf) {
+		copy(t.buf, p[len(p)-len(t.buf):])
+		t.pos, t.full = 0, true
+		return
+	}
+
+	n := copy(t.buf[t.pos:], p)
+	copy(t.buf, p[n:])
+	if t.pos += len(p); t.pos >= len(t.buf) {
+		t.pos -= len(t.buf)
+		t.full = true
+	}
+}
+
+// last returns the copy of the last bytes if the total length
+// of the written bytes is size. Or, return nil.
+func (t *tailbuf) last(size int) []byte {
+	if t == nil || t.written != size {
+		return nil
+	} else if !t.full {
+		return append([]byte(nil), t.buf[:t.pos]...)
+	}
+
+	last := make([]byte, 0, len(t.buf))
+	last = append(last, t.buf[t.pos:]...)
+	return append(last, t.buf[:t.pos]...)
+}
+
+// gettruncatedtail returns tail that is the last truncatetail bytes
+// of the oversized body with the length size.
+//
+// If the option truncatetail is disabled or the tail is unknown, return nil.
+func gettruncatedtail(c *Config, size int, tail []byte) []byte {
+	n := c.TruncateTail
+	if n <= 0 || c.BodyMaxLen <= n || size <= c.BodyMaxLen || len(tail) != n {
+		return nil
+	}
+	return tail
+}
+
+// joinheadtail joins the head and tail of the body with the length size
+// by the marker "…[truncated N bytes]…".
+func joinheadtail(head, tail []byte, size int) []byte {
+	marker := "…[truncated " + strconv.Itoa(size-len(head)-len(tail)) + " bytes]…"
+	data := make([]byte, 0, len(head)+len(marker)+len(tail))
+	data = append(data, head...)
+	data = append(data, marker...)
+	return append(data, tail...)
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTailBuf(t *testing.T) {
+	tail := &tailbuf{buf: make([]byte, 4)}
+	for _, s := range []string{"ab", "c", "defg", "hi", "jklmnop", "q"} {
+		tail.write([]byte(s))
+	}
+
+	if v := string(tail.last(17)); v != "nopq" {
+		t.Errorf("expect tail '%s', but got '%s'", "nopq", v)
+	}
+	if v := tail.last(16); v != nil {
+		t.Errorf("unexpected tail '%s'", v)
+	}
+
+	tail = &tailbuf{buf: make([]byte, 4)}
+	tail.write([]byte("ab"))
+	if v := string(tail.last(2)); v != "ab" {
+		t.Errorf("expect tail '%s', but got '%s'", "ab", v)
+	}
+}
+
+func TestTruncateTail(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logRespBody.Set(true)
+	_ = logBodyMaxLen.Set(20)
+	_ = logTruncateBody.Set(true)
+	_ = logTruncateTail.Set(5)
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logRespBody.Set(false)
+		_ = logBodyMaxLen.Set(2048)
+		_ = logTruncateBody.Set(false)
+		_ = logTruncateTail.Set(0)
+	}()
+
+	const body = "abcdefghijklmnopqrstuvwxyz"
+	const expect = "abcdefghijklmno…[truncated 6 bytes]…vwxyz"
+
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/plain")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(body))
+	}, req)
+
+	for _, key := range []string{"reqbody", "respbody"} {
+		if v := attrs[key].String(); v != expect {
+			t.Errorf("expect %s '%s', but got '%s'", key, expect, v)
+		}
+		if v := attrs[key+"truncated"].Bool(); !v {
+			t.Errorf("expect %struncated, but got %v", key, attrs[key+"truncated"])
+		}
+	}
+
+	// The tail of the request body not read wholly is unknown.
+	req = httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/plain")
+	attrs = serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+	if v := attrs["reqbody"].String(); v != body[:20] {
+		t.Errorf("expect reqbody '%s', but got '%s'", body[:20], v)
+	}
+}
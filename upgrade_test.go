@@ -0,0 +1,93 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bufio"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsUpgrade(t *testing.T) {
+	header := http.Header{"Upgrade": {"websocket"}, "Connection": {"keep-alive, Upgrade"}}
+	if !isupgrade(header) {
+		t.Errorf("expect the upgrade request")
+	}
+
+	header.Set("Connection", "keep-alive")
+	if isupgrade(header) {
+		t.Errorf("unexpected the upgrade request")
+	}
+}
+
+func TestMiddlewareUpgrade(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logRespBody.Set(true)
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logRespBody.Set(false)
+	}()
+
+	handler := new(recordHandler)
+	server := httptest.NewServer(Middleware(slog.New(handler))(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if getResponseWriter(w) != nil {
+				t.Errorf("unexpected the wrapped response writer for the upgrade request")
+			}
+
+			conn, rw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("fail to hijack: %v", err)
+				return
+			}
+			defer conn.Close()
+
+			_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+				"Upgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+			_ = rw.Flush()
+		})))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, _ = conn.Write([]byte("GET /path HTTP/1.1\r\nHost: example.com\r\n" +
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+	line, _ := bufio.NewReader(conn).ReadString('\n')
+	if !strings.HasPrefix(line, "HTTP/1.1 101 ") {
+		t.Fatalf("unexpected the status line '%s'", line)
+	}
+
+	var attrs map[string]slog.Value
+	for i := 0; i < 100 && attrs == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		attrs = handler.last()
+	}
+
+	if v := attrs["status"].Int64(); v != http.StatusSwitchingProtocols {
+		t.Errorf("expect status %d, but got %d", http.StatusSwitchingProtocols, v)
+	}
+	if v, ok := attrs["respbodylen"]; ok {
+		t.Errorf("unexpected respbodylen %v", v)
+	}
+}
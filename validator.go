@@ -0,0 +1,50 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import "net/http"
+
+// BodyValidator is used to validate the whole body of the request
+// or response, such as against the schema of the OpenAPI specification,
+// and returns the violations if the body does not conform to it.
+//
+// direction is either "request" or "response". For the request,
+// status is 0 and header is the request header.
+type BodyValidator func(r *http.Request, direction string, status int, header http.Header, body []byte) (violations []string)
+
+var bodyValidator BodyValidator
+
+// SetBodyValidator sets the validator of the captured request and response
+// bodies, the violations of which are appended as the attribute
+// schema_violations by Collect, each prefixed with its direction.
+//
+// The truncated body is not validated.
+func SetBodyValidator(validator BodyValidator) {
+	bodyValidator = validator
+}
+
+// validatebody validates the body whose length is size, and returns nil
+// if no validator is set or the body is truncated.
+func validatebody(r *http.Request, direction string, status int, header http.Header, data []byte, size int) []string {
+	if bodyValidator == nil || len(data) != size {
+		return nil
+	}
+
+	violations := bodyValidator(r, direction, status, header, data)
+	for i, violation := range violations {
+		violations[i] = direction + ": " + violation
+	}
+	return violations
+}
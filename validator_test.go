@@ -0,0 +1,74 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBodyValidator(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logRespBody.Set(true)
+	SetBodyValidator(func(r *http.Request, direction string, status int, header http.Header, body []byte) []string {
+		if direction == "response" && status != http.StatusCreated {
+			return []string{"unexpected status"}
+		}
+		if !strings.HasPrefix(string(body), "{") {
+			return []string{"not an object"}
+		}
+		return nil
+	})
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logRespBody.Set(false)
+		SetBodyValidator(nil)
+	}()
+
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(`[1]`))
+	req.Header.Set("Content-Type", "application/json")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}, req)
+
+	expect := []string{"request: not an object", "response: unexpected status"}
+	if v, _ := attrs["schema_violations"].Any().([]string); !slices.Equal(v, expect) {
+		t.Errorf("expect schema_violations %v, but got %v", expect, v)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	attrs = serveAttrs(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}, req)
+
+	if v, ok := attrs["schema_violations"]; ok {
+		t.Errorf("unexpected schema_violations %v", v)
+	}
+
+	// The truncated body is not validated.
+	if v := validatebody(req, "request", 0, req.Header, []byte(`[1`), 3); v != nil {
+		t.Errorf("unexpected violations %v", v)
+	}
+}
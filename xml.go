@@ -0,0 +1,197 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// isxmlct reports whether ct is the content type of XML.
+func isxmlct(ct string) bool {
+	return ct == "application/xml" || ct == "text/xml" || strings.HasSuffix(ct, "+xml")
+}
+
+// transformxml returns a new XML document whose elements at the given paths
+// are replaced with RedactedValue, and strips the insignificant whitespaces
+// between the elements if compact is true. If indent is not empty,
+// the insignificant whitespaces are also stripped, and each element
+// begins on a new line indented by indent repeated by its depth.
+//
+// Each path is the dot-separated local names of the elements from the root,
+// such as "user.password", and "*" matches any element.
+func transformxml(data []byte, paths []string, compact bool, indent string) ([]byte, error) {
+	patterns := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		patterns = append(patterns, strings.Split(path, "."))
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)))
+	stack := make([]string, 0, 8)
+	nested := make([]bool, 0, 8) // Whether the element contains the child elements.
+	newline := func() {
+		if indent != "" && buf.Len() > 0 {
+			buf.WriteByte('\n')
+			buf.WriteString(strings.Repeat(indent, len(stack)))
+		}
+	}
+	for {
+		token, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if len(nested) > 0 {
+				nested[len(nested)-1] = true
+			}
+			newline()
+			writexmlstart(buf, t)
+			stack = append(stack, t.Name.Local)
+			nested = append(nested, false)
+			if matchxmlpath(patterns, stack) {
+				buf.WriteString(RedactedValue)
+				if err = skipxmlelement(dec); err != nil {
+					return nil, err
+				}
+				stack, nested = stack[:len(stack)-1], nested[:len(nested)-1]
+				writexmlend(buf, t.Name)
+			}
+
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, errors.New("unexpected xml end element")
+			}
+			stack = stack[:len(stack)-1]
+			if nested[len(nested)-1] {
+				newline()
+			}
+			nested = nested[:len(nested)-1]
+			writexmlend(buf, t.Name)
+
+		case xml.CharData:
+			if (compact || indent != "") && len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+			_ = xml.EscapeText(buf, t)
+
+		case xml.Comment:
+			if !compact {
+				newline()
+				buf.WriteString("<!--")
+				buf.Write(t)
+				buf.WriteString("-->")
+			}
+
+		case xml.ProcInst:
+			newline()
+			buf.WriteString("<?")
+			buf.WriteString(t.Target)
+			if len(t.Inst) > 0 {
+				buf.WriteByte(' ')
+				buf.Write(t.Inst)
+			}
+			buf.WriteString("?>")
+
+		case xml.Directive:
+			newline()
+			buf.WriteString("<!")
+			buf.Write(t)
+			buf.WriteByte('>')
+		}
+	}
+
+	if len(stack) > 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), nil
+}
+
+func matchxmlpath(patterns [][]string, stack []string) bool {
+	for _, pattern := range patterns {
+		if len(pattern) != len(stack) {
+			continue
+		}
+
+		matched := true
+		for i, name := range pattern {
+			if name != "*" && name != stack[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// skipxmlelement skips the tokens until the end of the current element.
+func skipxmlelement(dec *xml.Decoder) error {
+	for depth := 1; depth > 0; {
+		token, err := dec.RawToken()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return nil
+}
+
+func writexmlname(buf *bytes.Buffer, name xml.Name) {
+	if name.Space != "" {
+		buf.WriteString(name.Space)
+		buf.WriteByte(':')
+	}
+	buf.WriteString(name.Local)
+}
+
+func writexmlstart(buf *bytes.Buffer, t xml.StartElement) {
+	buf.WriteByte('<')
+	writexmlname(buf, t.Name)
+	for _, attr := range t.Attr {
+		buf.WriteByte(' ')
+		writexmlname(buf, attr.Name)
+		buf.WriteString(`="`)
+		_ = xml.EscapeText(buf, []byte(attr.Value))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('>')
+}
+
+func writexmlend(buf *bytes.Buffer, name xml.Name) {
+	buf.WriteString("</")
+	writexmlname(buf, name)
+	buf.WriteByte('>')
+}
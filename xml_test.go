@@ -0,0 +1,80 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loggerext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransformXML(t *testing.T) {
+	data := `<?xml version="1.0"?>
+<user id="1">
+  <!-- comment -->
+  <name>a &amp; b</name>
+  <password><v>secret</v></password>
+  <ns:token xmlns:ns="urn:x">abc</ns:token>
+</user>`
+
+	out, err := transformxml([]byte(data), []string{"user.password", "*.token"}, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `<?xml version="1.0"?><user id="1"><name>a &amp; b</name>` +
+		`<password>***</password><ns:token xmlns:ns="urn:x">***</ns:token></user>`
+	if string(out) != expect {
+		t.Errorf("expect '%s', but got '%s'", expect, out)
+	}
+
+	if _, err = transformxml([]byte(`<user><password>sec`), []string{"user.password"}, false, ""); err == nil {
+		t.Error("expect an error for the truncated xml")
+	}
+}
+
+func TestXMLBody(t *testing.T) {
+	_ = logReqBody.Set(true)
+	_ = logXML.Set(true)
+	_ = logXMLCompact.Set(true)
+	_ = logRedactXML.Set([]string{"Envelope.Body.password"})
+	defer func() {
+		_ = logReqBody.Set(false)
+		_ = logXML.Set(false)
+		_ = logXMLCompact.Set(false)
+		_ = logRedactXML.Set([]string{})
+	}()
+
+	body := "<Envelope>\n  <Body>\n    <password>secret</password>\n  </Body>\n</Envelope>"
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/soap+xml; charset=utf-8")
+	attrs := serveAttrs(func(w http.ResponseWriter, r *http.Request) {}, req)
+
+	expect := "<Envelope><Body><password>***</password></Body></Envelope>"
+	if v := attrs["reqbody"].String(); v != expect {
+		t.Errorf("expect reqbody '%s', but got '%s'", expect, v)
+	}
+}
+
+func TestTransformXMLIndent(t *testing.T) {
+	data := "<?xml version=\"1.0\"?>\n<a>\n<b>1</b> <c><d>2</d></c><e/></a>"
+	expect := "<?xml version=\"1.0\"?>\n<a>\n  <b>1</b>\n  <c>\n    <d>2</d>\n  </c>\n  <e></e>\n</a>"
+	if out, err := transformxml([]byte(data), nil, false, "  "); err != nil {
+		t.Error(err)
+	} else if string(out) != expect {
+		t.Errorf("expect '%s', but got '%s'", expect, out)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package zapext provides the zap adapter of Collect based on
+// "github.com/xgfone/go-apiserver-middleware-logger-ext".
+package zapext
+
+import (
+	"log/slog"
+	"net/http"
+
+	loggerext "github.com/xgfone/go-apiserver-middleware-logger-ext"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Collect is the same as loggerext.Collect, but returns the collected
+// attributes as the zap fields.
+//
+// Like loggerext.Collect, it must be called before loggerext.Release,
+// and the fields must be logged before the request is released.
+func Collect(w http.ResponseWriter, r *http.Request) []zap.Field {
+	fields := make([]zap.Field, 0, 16)
+	loggerext.Collect(w, r, func(attrs ...slog.Attr) {
+		for _, attr := range attrs {
+			fields = append(fields, Field(attr))
+		}
+	})
+	return fields
+}
+
+// Field converts the slog attribute to the zap field.
+func Field(attr slog.Attr) zap.Field {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return zap.String(attr.Key, value.String())
+	case slog.KindInt64:
+		return zap.Int64(attr.Key, value.Int64())
+	case slog.KindUint64:
+		return zap.Uint64(attr.Key, value.Uint64())
+	case slog.KindFloat64:
+		return zap.Float64(attr.Key, value.Float64())
+	case slog.KindBool:
+		return zap.Bool(attr.Key, value.Bool())
+	case slog.KindDuration:
+		return zap.Duration(attr.Key, value.Duration())
+	case slog.KindTime:
+		return zap.Time(attr.Key, value.Time())
+	case slog.KindGroup:
+		return zap.Object(attr.Key, group(value.Group()))
+	default:
+		return zap.Any(attr.Key, value.Any())
+	}
+}
+
+type group []slog.Attr
+
+func (g group) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, attr := range g {
+		Field(attr).AddTo(enc)
+	}
+	return nil
+}
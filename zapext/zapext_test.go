@@ -0,0 +1,74 @@
+// Copyright 2024 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zapext
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	loggerext "github.com/xgfone/go-apiserver-middleware-logger-ext"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestField(t *testing.T) {
+	enc := zapcore.NewMapObjectEncoder()
+	Field(slog.String("string", "abc")).AddTo(enc)
+	Field(slog.Int("int", 123)).AddTo(enc)
+	Field(slog.Bool("bool", true)).AddTo(enc)
+	Field(slog.Duration("duration", time.Second)).AddTo(enc)
+	Field(slog.Group("cookies", slog.String("sid", "***"))).AddTo(enc)
+
+	if v := enc.Fields["string"]; v != "abc" {
+		t.Errorf("expect '%s', but got '%v'", "abc", v)
+	}
+	if v := enc.Fields["int"]; v != int64(123) {
+		t.Errorf("expect %d, but got %v", 123, v)
+	}
+	if v := enc.Fields["bool"]; v != true {
+		t.Errorf("expect %v, but got %v", true, v)
+	}
+	if v := enc.Fields["duration"]; v != time.Second {
+		t.Errorf("expect %v, but got %v", time.Second, v)
+	}
+	if v, _ := enc.Fields["cookies"].(map[string]interface{}); v["sid"] != "***" {
+		t.Errorf("unexpected group %v", enc.Fields["cookies"])
+	}
+}
+
+func TestCollect(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("abc"))
+	req = req.WithContext(loggerext.WithRoute(req.Context(), "/path"))
+	req = req.WithContext(loggerext.EnableLogReqBody(req.Context()))
+	req.Header.Set("Content-Type", "text/plain")
+
+	w, r := loggerext.WrapReqRespBody(httptest.NewRecorder(), req)
+	defer loggerext.Release(w, r)
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range Collect(w, r) {
+		field.AddTo(enc)
+	}
+
+	if v := enc.Fields["route"]; v != "/path" {
+		t.Errorf("expect route '%s', but got '%v'", "/path", v)
+	}
+	if v := enc.Fields["reqbody"]; v != "abc" {
+		t.Errorf("expect reqbody '%s', but got '%v'", "abc", v)
+	}
+}